@@ -2,31 +2,66 @@ package main
 
 import (
 	"context"
+	"flag"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/ch629/bot-orchestrator/pkg/client"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+)
+
+var (
+	addr        = flag.String("addr", ":8080", "address of the orchestrator's gRPC server")
+	maxChannels = flag.Int("max-channels", 0, "most channels the bot can be in at once, 0 means no limit")
+	version     = flag.String("version", "", "build version of the bot, used for canary rollouts")
+	labels      = flag.String("labels", "", "comma separated key=value labels describing the bot")
 )
 
 func main() {
+	flag.Parse()
 	log, _ := zap.NewDevelopment()
 	zap.ReplaceGlobals(log)
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
-	conn, err := grpc.DialContext(ctx, ":8080", grpc.WithInsecure())
+	conn, err := grpc.DialContext(ctx, *addr, grpc.WithInsecure(), grpc.WithKeepaliveParams(keepalive.ClientParameters{
+		// Notice a dead connection to the orchestrator, the orchestrator doesn't allow pings more often than every 15s
+		Time:                30 * time.Second,
+		Timeout:             10 * time.Second,
+		PermitWithoutStream: true,
+	}))
 	if err != nil {
 		log.Fatal("failed to dial grpc", zap.Error(err))
 	}
-	id, err := client.Join(ctx, conn, newLogClient(log, cancel))
+	opts := []client.JoinOption{client.WithMaxChannels(*maxChannels)}
+	if *version != "" {
+		opts = append(opts, client.WithVersion(*version))
+	}
+	if *labels != "" {
+		opts = append(opts, client.WithLabels(parseLabels(*labels)))
+	}
+	session, err := client.Connect(ctx, conn, newLogClient(log, cancel), opts...)
 	if err != nil {
-		log.Fatal("failed to join client", zap.Error(err))
+		log.Fatal("failed to connect client", zap.Error(err))
 	}
-	log.Info("joined orchestrator", zap.String("bot_id", id.String()))
+	log.Info("connected to orchestrator", zap.String("bot_id", session.ID().String()))
 	<-ctx.Done()
 }
 
+// parseLabels parses comma separated key=value pairs
+func parseLabels(s string) map[string]string {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		if kv := strings.SplitN(pair, "=", 2); len(kv) == 2 {
+			labels[kv[0]] = kv[1]
+		}
+	}
+	return labels
+}
+
 func newLogClient(logger *zap.Logger, cancel context.CancelFunc) *LogClient {
 	return &LogClient{
 		logger: logger,
@@ -2,17 +2,32 @@ package main
 
 import (
 	"context"
+	"flag"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/ch629/bot-orchestrator/internal/pkg/api"
 	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
+	"github.com/ch629/bot-orchestrator/internal/pkg/proto"
+	"github.com/ch629/bot-orchestrator/internal/pkg/scale"
 	"github.com/ch629/bot-orchestrator/internal/pkg/server"
 	"go.uber.org/zap"
 )
 
+var (
+	clientBinary      = flag.String("client-binary", "", "path to a build of cmd/client to autoscale bots with, bots aren't started or stopped if empty")
+	channelsPerBot    = flag.Int("channels-per-bot", 100, "number of channels each bot should run when autoscaling")
+	loadPerBot        = flag.Float64("load-per-bot", 0, "messages per second each bot should handle when autoscaling, 0 ignores load")
+	heartbeatInterval = flag.Duration("heartbeat-interval", 10*time.Second, "how often bots send heartbeats")
+	livenessTimeout   = flag.Duration("liveness-timeout", 30*time.Second, "how long a bot can go without a heartbeat before it's evicted, 0 never evicts bots")
+	gracePeriod       = flag.Duration("grace-period", 30*time.Second, "how long a disconnected bot's channels are kept for it to reconnect, 0 moves them straight away")
+	exclusionTTL      = flag.Duration("exclusion-ttl", 24*time.Hour, "how long a bot or account is excluded from a channel after being banned from it")
+)
+
 // grpcurl -plaintext -import-path ./pkg/proto/ -proto orchestrator.proto -d '{}' localhost:8080 Orchestrator/JoinStream
 func main() {
+	flag.Parse()
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
@@ -20,15 +35,29 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	botsService := bots.New(logger)
+	botsService := bots.New(logger, bots.WithLivenessTimeout(*livenessTimeout), bots.WithSessionGracePeriod(*gracePeriod), bots.WithExclusionTTL(*exclusionTTL))
+	// Twitch allows 20 JOINs every 10 seconds for each account
+	dispatcher := proto.NewDispatcher(logger, proto.Limit{Rate: 2, Burst: 20}, proto.Limit{Rate: 2, Burst: 20})
+	var scaler scale.Scaler
+	if *clientBinary != "" {
+		scaler = scale.NewLocalScaler(logger, *clientBinary)
+	}
+	autoscaler := scale.New(logger, botsService, scaler, scale.Target{
+		ChannelsPerBot: *channelsPerBot,
+		LoadPerBot:     *loadPerBot,
+		Min:            1,
+	})
+	if scaler != nil {
+		go autoscaler.Run(ctx)
+	}
 	logger.Info("starting gRPC server")
 	go func() {
-		if err := server.New(logger, botsService).Start(ctx, 8080); err != nil {
+		if err := server.New(logger, botsService, dispatcher, server.WithHeartbeatInterval(*heartbeatInterval)).Start(ctx, 8080); err != nil {
 			logger.Fatal("failed to start gRPC server", zap.Error(err))
 		}
 	}()
 	go func() {
-		httpServer := api.New(ctx, logger, botsService)
+		httpServer := api.New(ctx, logger, botsService, api.WithAutoscaler(autoscaler))
 		if err := httpServer.Start("localhost:9080"); err != nil {
 			logger.Error("failed to start http server", zap.Error(err))
 		}
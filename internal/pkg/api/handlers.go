@@ -5,7 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
+	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
 
@@ -13,6 +17,16 @@ import (
 func (s *server) JoinChannel() http.HandlerFunc {
 	type request struct {
 		Channel string `json:"channel"`
+		// Replicas overrides the default number of bots to run the channel on
+		Replicas int `json:"replicas"`
+		// BotID pins the channel to a bot
+		BotID uuid.UUID `json:"bot_id"`
+		// NodeSelector only places the channel on bots with all of these labels
+		NodeSelector map[string]string `json:"node_selector"`
+		// AntiAffinity are label keys each replica must be on a bot with a different value for, e.g. "host"
+		AntiAffinity []string `json:"anti_affinity"`
+		// Priority places the channel ahead of, and lets it evict, lower priority channels when bots are full
+		Priority int `json:"priority"`
 	}
 
 	return func(rw http.ResponseWriter, r *http.Request) {
@@ -30,9 +44,35 @@ func (s *server) JoinChannel() http.HandlerFunc {
 			return
 		}
 
-		if err := s.botService.JoinChannel(req.Channel); err != nil {
-			// TODO: Handle
-			_ = writeErr(rw, fmt.Errorf("failed to join channel: %w", err), http.StatusInternalServerError)
+		if req.Replicas < 0 {
+			_ = writeErr(rw, errors.New("replicas must not be negative"), http.StatusBadRequest)
+			return
+		}
+
+		var opts []bots.ChannelOption
+		if req.Replicas != 0 {
+			opts = append(opts, bots.WithReplicas(req.Replicas))
+		}
+		if req.BotID != uuid.Nil {
+			opts = append(opts, bots.WithPinnedBot(req.BotID))
+		}
+		if len(req.NodeSelector) > 0 {
+			opts = append(opts, bots.WithNodeSelector(req.NodeSelector))
+		}
+		for _, key := range req.AntiAffinity {
+			opts = append(opts, bots.WithAntiAffinity(key))
+		}
+		if req.Priority != 0 {
+			opts = append(opts, bots.WithPriority(req.Priority))
+		}
+
+		if err := s.botService.JoinChannel(req.Channel, opts...); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, bots.ErrBotNotExist) {
+				// The bot the channel is pinned to is part of the request
+				status = http.StatusBadRequest
+			}
+			_ = writeErr(rw, fmt.Errorf("failed to join channel: %w", err), status)
 			return
 		}
 		rw.WriteHeader(http.StatusOK)
@@ -77,6 +117,16 @@ func (s *server) BotInfo() http.HandlerFunc {
 	}
 }
 
+// RemovableBots is the handler to list the bots which are empty and can be scaled down
+func (s *server) RemovableBots() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		removable := s.botService.RemovableBots()
+		if err := writeJSON(rw, removable, http.StatusOK); err != nil {
+			s.logger.Error("failed to write JSON", zap.Error(err))
+		}
+	}
+}
+
 func (s *server) ChannelInfo() http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
 		chanInfo := s.botService.ChannelInfo()
@@ -86,6 +136,372 @@ func (s *server) ChannelInfo() http.HandlerFunc {
 	}
 }
 
+// MoveChannel is the handler to move a channel from one bot to another
+func (s *server) MoveChannel() http.HandlerFunc {
+	type request struct {
+		Channel string    `json:"channel"`
+		From    uuid.UUID `json:"from"`
+		To      uuid.UUID `json:"to"`
+	}
+
+	return func(rw http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			_ = writeErr(rw, fmt.Errorf("received json invalid request body: %w", err), http.StatusBadRequest)
+			return
+		}
+
+		if req.Channel == "" {
+			_ = writeErr(rw, errors.New("missing channel in request"), http.StatusBadRequest)
+			return
+		}
+
+		if req.From == uuid.Nil || req.To == uuid.Nil {
+			_ = writeErr(rw, errors.New("missing from or to bot in request"), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.botService.MoveChannel(req.Channel, req.From, req.To); err != nil {
+			_ = writeErr(rw, fmt.Errorf("failed to move channel: %w", err), http.StatusInternalServerError)
+			return
+		}
+		rw.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (s *server) Migrations() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		migrations := s.botService.Migrations()
+		if err := writeJSON(rw, migrations, http.StatusOK); err != nil {
+			s.logger.Error("failed to write JSON", zap.Error(err))
+		}
+	}
+}
+
+func (s *server) PendingChannels() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		pending := s.botService.PendingChannels()
+		if err := writeJSON(rw, pending, http.StatusOK); err != nil {
+			s.logger.Error("failed to write JSON", zap.Error(err))
+		}
+	}
+}
+
+// FailedChannels is the handler to list the channels given up on because bots couldn't join them
+func (s *server) FailedChannels() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		failed := s.botService.FailedChannels()
+		if err := writeJSON(rw, failed, http.StatusOK); err != nil {
+			s.logger.Error("failed to write JSON", zap.Error(err))
+		}
+	}
+}
+
+// Exclusions is the handler to list the bots and accounts excluded from channels
+func (s *server) Exclusions() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if err := writeJSON(rw, s.botService.Exclusions(), http.StatusOK); err != nil {
+			s.logger.Error("failed to write JSON", zap.Error(err))
+		}
+	}
+}
+
+// Exclude is the handler to stop a channel being placed on a bot or account, for ttl_seconds or the default TTL if
+// it isn't set
+func (s *server) Exclude() http.HandlerFunc {
+	type request struct {
+		Channel    string     `json:"channel"`
+		BotID      *uuid.UUID `json:"bot_id"`
+		Account    string     `json:"account"`
+		Reason     string     `json:"reason"`
+		TTLSeconds int        `json:"ttl_seconds"`
+	}
+
+	return func(rw http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			_ = writeErr(rw, fmt.Errorf("received json invalid request body: %w", err), http.StatusBadRequest)
+			return
+		}
+		if req.TTLSeconds < 0 {
+			_ = writeErr(rw, errors.New("ttl_seconds must not be negative"), http.StatusBadRequest)
+			return
+		}
+		exclusion := bots.Exclusion{
+			Channel: req.Channel,
+			BotID:   req.BotID,
+			Account: req.Account,
+			Reason:  req.Reason,
+		}
+		if req.TTLSeconds > 0 {
+			exclusion.ExpiresAt = time.Now().Add(time.Duration(req.TTLSeconds) * time.Second)
+		}
+		if err := s.botService.Exclude(exclusion); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, bots.ErrInvalidExclusion) {
+				status = http.StatusBadRequest
+			}
+			_ = writeErr(rw, fmt.Errorf("failed to exclude: %w", err), status)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	}
+}
+
+// ClearExclusions is the handler to remove the exclusions matching the channel, bot_id and account query parameters,
+// every exclusion if none are given
+func (s *server) ClearExclusions() http.HandlerFunc {
+	type response struct {
+		Cleared int `json:"cleared"`
+	}
+
+	return func(rw http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter := bots.Exclusion{
+			Channel: query.Get("channel"),
+			Account: query.Get("account"),
+		}
+		if value := query.Get("bot_id"); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				_ = writeErr(rw, fmt.Errorf("invalid bot id: %w", err), http.StatusBadRequest)
+				return
+			}
+			filter.BotID = &id
+		}
+		cleared := s.botService.ClearExclusions(filter)
+		if err := writeJSON(rw, response{Cleared: cleared}, http.StatusOK); err != nil {
+			s.logger.Error("failed to write JSON", zap.Error(err))
+		}
+	}
+}
+
+// UnschedulableChannels is the handler to list the channels no bot can run because of their placement rules
+func (s *server) UnschedulableChannels() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		unschedulable := s.botService.UnschedulableChannels()
+		if err := writeJSON(rw, unschedulable, http.StatusOK); err != nil {
+			s.logger.Error("failed to write JSON", zap.Error(err))
+		}
+	}
+}
+
+// CordonBot is the handler to stop new channels being placed on a bot
+func (s *server) CordonBot() http.HandlerFunc {
+	return s.botAction("cordon", s.botService.Cordon)
+}
+
+// UncordonBot is the handler to allow new channels to be placed on a bot again
+func (s *server) UncordonBot() http.HandlerFunc {
+	return s.botAction("uncordon", s.botService.Uncordon)
+}
+
+// DrainBot is the handler to move all channels off a bot before it is shut down
+func (s *server) DrainBot() http.HandlerFunc {
+	return s.botAction("drain", s.botService.Drain)
+}
+
+// botAction builds a handler which calls action with the bot ID in the path
+func (s *server) botAction(name string, action func(id uuid.UUID) error) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		id, err := botID(r)
+		if err != nil {
+			_ = writeErr(rw, err, http.StatusBadRequest)
+			return
+		}
+
+		if err := action(id); err != nil {
+			_ = writeErr(rw, fmt.Errorf("failed to %s bot: %w", name, err), botErrStatus(err))
+			return
+		}
+		rw.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func (s *server) DrainStatus() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		id, err := botID(r)
+		if err != nil {
+			_ = writeErr(rw, err, http.StatusBadRequest)
+			return
+		}
+
+		status, err := s.botService.DrainStatus(id)
+		if err != nil {
+			_ = writeErr(rw, fmt.Errorf("failed to get drain status: %w", err), botErrStatus(err))
+			return
+		}
+		if err := writeJSON(rw, status, http.StatusOK); err != nil {
+			s.logger.Error("failed to write JSON", zap.Error(err))
+		}
+	}
+}
+
+// RebalancePlan is the handler to preview the moves a rebalance would make
+func (s *server) RebalancePlan() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		plan := s.botService.PlanRebalance()
+		if err := writeJSON(rw, plan, http.StatusOK); err != nil {
+			s.logger.Error("failed to write JSON", zap.Error(err))
+		}
+	}
+}
+
+// Rebalance is the handler to start moving channels to balance the bots
+func (s *server) Rebalance() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		plan := s.botService.Rebalance()
+		if err := writeJSON(rw, plan, http.StatusAccepted); err != nil {
+			s.logger.Error("failed to write JSON", zap.Error(err))
+		}
+	}
+}
+
+// Groups is the handler to list the channels in each co-location group
+func (s *server) Groups() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if err := writeJSON(rw, s.botService.Groups(), http.StatusOK); err != nil {
+			s.logger.Error("failed to write JSON", zap.Error(err))
+		}
+	}
+}
+
+// SetGroup is the handler to set the channels in a co-location group, which are always placed on the same bots
+func (s *server) SetGroup() http.HandlerFunc {
+	type request struct {
+		Channels []string `json:"channels"`
+	}
+
+	return func(rw http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			_ = writeErr(rw, fmt.Errorf("received json invalid request body: %w", err), http.StatusBadRequest)
+			return
+		}
+		if err := s.botService.SetGroup(mux.Vars(r)["name"], req.Channels); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, bots.ErrInGroup) {
+				status = http.StatusConflict
+			} else if errors.Is(err, bots.ErrEmptyGroup) {
+				status = http.StatusBadRequest
+			}
+			_ = writeErr(rw, fmt.Errorf("failed to set group: %w", err), status)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	}
+}
+
+// DeleteGroup is the handler to stop placing a group's channels together
+func (s *server) DeleteGroup() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if err := s.botService.DeleteGroup(mux.Vars(r)["name"]); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, bots.ErrGroupNotExist) {
+				status = http.StatusNotFound
+			}
+			_ = writeErr(rw, fmt.Errorf("failed to delete group: %w", err), status)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	}
+}
+
+// Canary is the handler to get the progress of a canary rollout
+func (s *server) Canary() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if err := writeJSON(rw, s.botService.Canary(), http.StatusOK); err != nil {
+			s.logger.Error("failed to write JSON", zap.Error(err))
+		}
+	}
+}
+
+// SetCanary is the handler to roll a new bot version out to a percentage of channels, or to a list of channels
+func (s *server) SetCanary() http.HandlerFunc {
+	type request struct {
+		Version  string   `json:"version"`
+		Percent  int      `json:"percent"`
+		Channels []string `json:"channels"`
+	}
+
+	return func(rw http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			_ = writeErr(rw, fmt.Errorf("received json invalid request body: %w", err), http.StatusBadRequest)
+			return
+		}
+		if req.Version == "" {
+			_ = writeErr(rw, errors.New("missing version in request"), http.StatusBadRequest)
+			return
+		}
+		if err := s.botService.SetCanary(req.Version, req.Percent, req.Channels); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, bots.ErrInvalidPercent) {
+				status = http.StatusBadRequest
+			}
+			_ = writeErr(rw, fmt.Errorf("failed to set canary: %w", err), status)
+			return
+		}
+		rw.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// PromoteCanary is the handler to move every channel onto the canary version
+func (s *server) PromoteCanary() http.HandlerFunc {
+	return s.canaryAction("promote", s.botService.PromoteCanary)
+}
+
+// RollbackCanary is the handler to move every channel off the canary version
+func (s *server) RollbackCanary() http.HandlerFunc {
+	return s.canaryAction("roll back", s.botService.RollbackCanary)
+}
+
+// canaryAction builds a handler which calls action on the current canary
+func (s *server) canaryAction(name string, action func() error) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if err := action(); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, bots.ErrNoCanary) {
+				status = http.StatusConflict
+			}
+			_ = writeErr(rw, fmt.Errorf("failed to %s canary: %w", name, err), status)
+			return
+		}
+		rw.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// Scale is the handler to get how many bots are running and how many the autoscaler wants
+func (s *server) Scale() http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		rec := s.autoscaler.Recommendation()
+		if err := writeJSON(rw, rec, http.StatusOK); err != nil {
+			s.logger.Error("failed to write JSON", zap.Error(err))
+		}
+	}
+}
+
+// botID parses the bot ID from the request path
+func botID(r *http.Request) (uuid.UUID, error) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid bot id: %w", err)
+	}
+	return id, nil
+}
+
+// botErrStatus returns the status code for an error returned for a specific bot
+func botErrStatus(err error) int {
+	if errors.Is(err, bots.ErrBotNotExist) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
 // writeJSON writes a JSON payload back to the ResponseWriter with a status code
 func writeJSON(rw http.ResponseWriter, payload interface{}, status int) error {
 	rw.Header().Add("Content-Type", "application/json")
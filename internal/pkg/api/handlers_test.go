@@ -3,13 +3,20 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
 	"github.com/ch629/bot-orchestrator/internal/pkg/bots/mocks"
+	"github.com/ch629/bot-orchestrator/internal/pkg/scale"
+	scaleMocks "github.com/ch629/bot-orchestrator/internal/pkg/scale/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zaptest"
 )
@@ -31,6 +38,74 @@ func Test_ServerJoinChannel(t *testing.T) {
 				require.Equal(t, http.StatusOK, resp.StatusCode)
 			},
 		},
+		{
+			name: "Success: Valid request with replicas",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("JoinChannel", "foo", mock.Anything).Return(nil)
+			},
+			payload: `{"channel": "foo", "replicas": 2}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+			},
+		},
+		{
+			name: "Success: Valid request pinned to a bot",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("JoinChannel", "foo", mock.Anything).Return(nil)
+			},
+			payload: `{"channel": "foo", "bot_id": "5c6b4a3e-2f1d-4e0c-9b8a-7f6e5d4c3b2a"}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+			},
+		},
+		{
+			name: "Success: Valid request with placement rules",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("JoinChannel", "foo", mock.Anything, mock.Anything).Return(nil)
+			},
+			payload: `{"channel": "foo", "node_selector": {"account": "modbot"}, "anti_affinity": ["host"]}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+			},
+		},
+		{
+			name: "Success: Valid request with priority",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("JoinChannel", "foo", mock.Anything).Return(nil)
+			},
+			payload: `{"channel": "foo", "priority": 10}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+			},
+		},
+		{
+			name: "Failure: Pinned to a bot which doesn't exist",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("JoinChannel", "foo", mock.Anything).Return(bots.ErrBotNotExist)
+			},
+			payload: `{"channel": "foo", "bot_id": "5c6b4a3e-2f1d-4e0c-9b8a-7f6e5d4c3b2a"}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+				bs, _ := ioutil.ReadAll(resp.Body)
+				require.JSONEq(t, `{"error":"failed to join channel: bot does not exist"}`, string(bs))
+			},
+		},
+		{
+			name:    "Failure: Invalid bot ID",
+			payload: `{"channel": "foo", "bot_id": "bar"}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			},
+		},
+		{
+			name:    "Failure: Negative replicas",
+			payload: `{"channel": "foo", "replicas": -1}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+				bs, _ := ioutil.ReadAll(resp.Body)
+				require.JSONEq(t, `{"error":"replicas must not be negative"}`, string(bs))
+			},
+		},
 		{
 			name:    "Failure: Invalid JSON request",
 			payload: `{`,
@@ -81,3 +156,585 @@ func Test_ServerJoinChannel(t *testing.T) {
 		})
 	}
 }
+
+func Test_ServerMoveChannel(t *testing.T) {
+	from, to := uuid.New(), uuid.New()
+	tests := []struct {
+		name       string
+		setupMocks func(mockBotService *mocks.Service)
+		payload    string
+		assertions func(t *testing.T, resp http.Response)
+	}{
+		{
+			name: "Success: Valid request",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("MoveChannel", "foo", from, to).Return(nil)
+			},
+			payload: fmt.Sprintf(`{"channel": "foo", "from": %q, "to": %q}`, from, to),
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusAccepted, resp.StatusCode)
+			},
+		},
+		{
+			name:    "Failure: Missing bot",
+			payload: fmt.Sprintf(`{"channel": "foo", "from": %q}`, from),
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+				bs, _ := ioutil.ReadAll(resp.Body)
+				require.JSONEq(t, `{"error":"missing from or to bot in request"}`, string(bs))
+			},
+		},
+		{
+			name: "Failure: Error moving channel",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("MoveChannel", "foo", from, to).Return(errors.New("failure"))
+			},
+			payload: fmt.Sprintf(`{"channel": "foo", "from": %q, "to": %q}`, from, to),
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+				bs, _ := ioutil.ReadAll(resp.Body)
+				require.JSONEq(t, `{"error":"failed to move channel: failure"}`, string(bs))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/move", strings.NewReader(tt.payload))
+			rw := httptest.NewRecorder()
+			mockBotsService := &mocks.Service{}
+			if tt.setupMocks != nil {
+				tt.setupMocks(mockBotsService)
+			}
+			server := New(context.Background(), zaptest.NewLogger(t), mockBotsService)
+			server.MoveChannel().ServeHTTP(rw, req)
+
+			res := rw.Result()
+			defer res.Body.Close()
+			tt.assertions(t, *res)
+			mockBotsService.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_ServerPendingChannels(t *testing.T) {
+	queuedAt := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	mockBotsService := &mocks.Service{}
+	mockBotsService.On("PendingChannels").Return([]bots.PendingChannel{
+		{Channel: "foo", QueuedAt: queuedAt, WaitSeconds: 1.5, Priority: 2},
+	})
+	req := httptest.NewRequest("GET", "/api/v1/pending", nil)
+	rw := httptest.NewRecorder()
+	server := New(context.Background(), zaptest.NewLogger(t), mockBotsService)
+	server.PendingChannels().ServeHTTP(rw, req)
+
+	res := rw.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	bs, _ := ioutil.ReadAll(res.Body)
+	require.JSONEq(t, `[{"channel":"foo","queued_at":"2021-09-01T12:00:00Z","wait_seconds":1.5,"priority":2}]`, string(bs))
+	mockBotsService.AssertExpectations(t)
+}
+
+func Test_ServerFailedChannels(t *testing.T) {
+	failedAt := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	mockBotsService := &mocks.Service{}
+	mockBotsService.On("FailedChannels").Return([]bots.FailedChannel{
+		{Channel: "foo", Reason: "channel_not_found", Error: "channel not found", Attempts: 1, FailedAt: failedAt},
+	})
+	req := httptest.NewRequest("GET", "/api/v1/failed", nil)
+	rw := httptest.NewRecorder()
+	server := New(context.Background(), zaptest.NewLogger(t), mockBotsService)
+	server.createRoutes().ServeHTTP(rw, req)
+
+	res := rw.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	bs, _ := ioutil.ReadAll(res.Body)
+	require.JSONEq(t, `[{"channel":"foo","reason":"channel_not_found","error":"channel not found","attempts":1,"failed_at":"2021-09-01T12:00:00Z"}]`, string(bs))
+	mockBotsService.AssertExpectations(t)
+}
+
+func Test_ServerExclude(t *testing.T) {
+	id := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	tests := []struct {
+		name       string
+		setupMocks func(mockBotService *mocks.Service)
+		payload    string
+		assertions func(t *testing.T, resp http.Response)
+	}{
+		{
+			name: "Success: Exclude a bot",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("Exclude", bots.Exclusion{Channel: "foo", BotID: &id, Reason: "spam"}).Return(nil)
+			},
+			payload: `{"channel": "foo", "bot_id": "00000000-0000-0000-0000-000000000001", "reason": "spam"}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+			},
+		},
+		{
+			name: "Success: Exclude an account with a TTL",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("Exclude", mock.MatchedBy(func(e bots.Exclusion) bool {
+					return e.Account == "modbot" && time.Until(e.ExpiresAt) > 59*time.Minute
+				})).Return(nil)
+			},
+			payload: `{"channel": "foo", "account": "modbot", "ttl_seconds": 3600}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+			},
+		},
+		{
+			name:    "Failure: Negative TTL",
+			payload: `{"channel": "foo", "account": "modbot", "ttl_seconds": -1}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+				bs, _ := ioutil.ReadAll(resp.Body)
+				require.JSONEq(t, `{"error":"ttl_seconds must not be negative"}`, string(bs))
+			},
+		},
+		{
+			name: "Failure: Invalid exclusion",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("Exclude", bots.Exclusion{Channel: "foo"}).Return(bots.ErrInvalidExclusion)
+			},
+			payload: `{"channel": "foo"}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/exclusion", strings.NewReader(tt.payload))
+			rw := httptest.NewRecorder()
+			mockBotsService := &mocks.Service{}
+			if tt.setupMocks != nil {
+				tt.setupMocks(mockBotsService)
+			}
+			server := New(context.Background(), zaptest.NewLogger(t), mockBotsService)
+			server.createRoutes().ServeHTTP(rw, req)
+
+			res := rw.Result()
+			defer res.Body.Close()
+			tt.assertions(t, *res)
+			mockBotsService.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_ServerExclusions(t *testing.T) {
+	id := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	expiresAt := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	mockBotsService := &mocks.Service{}
+	mockBotsService.On("Exclusions").Return([]bots.Exclusion{
+		{Channel: "foo", Account: "modbot", Reason: "banned", ExpiresAt: expiresAt},
+		{Channel: "foo", BotID: &id, Reason: "error", ExpiresAt: expiresAt},
+	})
+	mockBotsService.On("ClearExclusions", bots.Exclusion{Channel: "foo", BotID: &id}).Return(1)
+	server := New(context.Background(), zaptest.NewLogger(t), mockBotsService)
+
+	rw := httptest.NewRecorder()
+	server.createRoutes().ServeHTTP(rw, httptest.NewRequest("GET", "/api/v1/exclusion", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+	require.JSONEq(t, `[{"channel":"foo","account":"modbot","reason":"banned","expires_at":"2021-09-01T12:00:00Z"},{"channel":"foo","bot_id":"00000000-0000-0000-0000-000000000001","reason":"error","expires_at":"2021-09-01T12:00:00Z"}]`, rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	server.createRoutes().ServeHTTP(rw, httptest.NewRequest("DELETE", "/api/v1/exclusion?channel=foo&bot_id=00000000-0000-0000-0000-000000000001", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+	require.JSONEq(t, `{"cleared":1}`, rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	server.createRoutes().ServeHTTP(rw, httptest.NewRequest("DELETE", "/api/v1/exclusion?bot_id=bar", nil))
+	require.Equal(t, http.StatusBadRequest, rw.Code)
+	mockBotsService.AssertExpectations(t)
+}
+
+func Test_ServerUnschedulableChannels(t *testing.T) {
+	mockBotsService := &mocks.Service{}
+	mockBotsService.On("UnschedulableChannels").Return([]bots.UnschedulableChannel{
+		{Channel: "foo", MissingReplicas: 1, Reason: "no bot has labels account=modbot"},
+	})
+	req := httptest.NewRequest("GET", "/api/v1/unschedulable", nil)
+	rw := httptest.NewRecorder()
+	server := New(context.Background(), zaptest.NewLogger(t), mockBotsService)
+	server.createRoutes().ServeHTTP(rw, req)
+
+	res := rw.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	bs, _ := ioutil.ReadAll(res.Body)
+	require.JSONEq(t, `[{"channel":"foo","missing_replicas":1,"reason":"no bot has labels account=modbot"}]`, string(bs))
+	mockBotsService.AssertExpectations(t)
+}
+
+func Test_ServerBotInfo(t *testing.T) {
+	id := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	mockBotsService := &mocks.Service{}
+	mockBotsService.On("BotInfo").Return([]bots.BotInfo{
+		{
+			ID:              id,
+			Channels:        []string{"foo"},
+			Labels:          map[string]string{"account": "modbot"},
+			Version:         "1.2.0",
+			Hostname:        "bot-1",
+			Platform:        "linux/amd64",
+			ProtocolVersion: 2,
+		},
+	})
+	req := httptest.NewRequest("GET", "/api/v1/bot", nil)
+	rw := httptest.NewRecorder()
+	server := New(context.Background(), zaptest.NewLogger(t), mockBotsService)
+	server.createRoutes().ServeHTTP(rw, req)
+
+	res := rw.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	bs, _ := ioutil.ReadAll(res.Body)
+	require.JSONEq(t, `[{"id":"00000000-0000-0000-0000-000000000001","channels":["foo"],"load":0,"max_channels":0,"cordoned":false,"draining":false,"queue_depth":0,"labels":{"account":"modbot"},"version":"1.2.0","joining":null,"last_heartbeat":null,"hostname":"bot-1","platform":"linux/amd64","protocol_version":2}]`, string(bs))
+	mockBotsService.AssertExpectations(t)
+}
+
+func Test_ServerRemovableBots(t *testing.T) {
+	id := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	mockBotsService := &mocks.Service{}
+	mockBotsService.On("RemovableBots").Return([]bots.BotInfo{
+		{ID: id, Channels: []string{}},
+	})
+	req := httptest.NewRequest("GET", "/api/v1/bot/removable", nil)
+	rw := httptest.NewRecorder()
+	server := New(context.Background(), zaptest.NewLogger(t), mockBotsService)
+	server.createRoutes().ServeHTTP(rw, req)
+
+	res := rw.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	bs, _ := ioutil.ReadAll(res.Body)
+	require.JSONEq(t, `[{"id":"00000000-0000-0000-0000-000000000001","channels":[],"load":0,"max_channels":0,"cordoned":false,"draining":false,"queue_depth":0,"labels":null,"version":"","joining":null,"last_heartbeat":null,"hostname":"","platform":"","protocol_version":0}]`, string(bs))
+	mockBotsService.AssertExpectations(t)
+}
+
+func Test_ServerDrainBot(t *testing.T) {
+	id := uuid.New()
+	tests := []struct {
+		name       string
+		setupMocks func(mockBotService *mocks.Service)
+		path       string
+		assertions func(t *testing.T, resp http.Response)
+	}{
+		{
+			name: "Success: Valid request",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("Drain", id).Return(nil)
+			},
+			path: fmt.Sprintf("/api/v1/bot/%s/drain", id),
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusAccepted, resp.StatusCode)
+			},
+		},
+		{
+			name: "Failure: Invalid bot ID",
+			path: "/api/v1/bot/foo/drain",
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+				bs, _ := ioutil.ReadAll(resp.Body)
+				require.JSONEq(t, `{"error":"invalid bot id: invalid UUID length: 3"}`, string(bs))
+			},
+		},
+		{
+			name: "Failure: Bot doesn't exist",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("Drain", id).Return(bots.ErrBotNotExist)
+			},
+			path: fmt.Sprintf("/api/v1/bot/%s/drain", id),
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusNotFound, resp.StatusCode)
+				bs, _ := ioutil.ReadAll(resp.Body)
+				require.JSONEq(t, `{"error":"failed to drain bot: bot does not exist"}`, string(bs))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, nil)
+			rw := httptest.NewRecorder()
+			mockBotsService := &mocks.Service{}
+			if tt.setupMocks != nil {
+				tt.setupMocks(mockBotsService)
+			}
+			server := New(context.Background(), zaptest.NewLogger(t), mockBotsService)
+			server.createRoutes().ServeHTTP(rw, req)
+
+			res := rw.Result()
+			defer res.Body.Close()
+			tt.assertions(t, *res)
+			mockBotsService.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_ServerRebalancePlan(t *testing.T) {
+	from := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	to := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	mockBotsService := &mocks.Service{}
+	mockBotsService.On("PlanRebalance").Return(bots.RebalancePlan{
+		Moves:  []bots.Move{{Channel: "foo", From: from, To: to}},
+		Before: map[uuid.UUID]int{from: 2, to: 0},
+		After:  map[uuid.UUID]int{from: 1, to: 1},
+	})
+	req := httptest.NewRequest("GET", "/api/v1/rebalance/plan", nil)
+	rw := httptest.NewRecorder()
+	server := New(context.Background(), zaptest.NewLogger(t), mockBotsService)
+	server.createRoutes().ServeHTTP(rw, req)
+
+	res := rw.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	bs, _ := ioutil.ReadAll(res.Body)
+	require.JSONEq(t, fmt.Sprintf(`{
+		"moves": [{"channel": "foo", "from": %[1]q, "to": %[2]q}],
+		"before": {%[1]q: 2, %[2]q: 0},
+		"after": {%[1]q: 1, %[2]q: 1}
+	}`, from, to), string(bs))
+	mockBotsService.AssertExpectations(t)
+}
+
+func Test_ServerScale(t *testing.T) {
+	mockAutoscaler := &scaleMocks.Autoscaler{}
+	mockAutoscaler.On("Recommendation").Return(scale.Recommendation{Current: 2, Desired: 3, Channels: 250, Load: 12.5})
+	req := httptest.NewRequest("GET", "/api/v1/scale", nil)
+	rw := httptest.NewRecorder()
+	server := New(context.Background(), zaptest.NewLogger(t), &mocks.Service{}, WithAutoscaler(mockAutoscaler))
+	server.createRoutes().ServeHTTP(rw, req)
+
+	res := rw.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	bs, _ := ioutil.ReadAll(res.Body)
+	require.JSONEq(t, `{"current":2,"desired":3,"channels":250,"load":12.5}`, string(bs))
+	mockAutoscaler.AssertExpectations(t)
+
+	// Without an autoscaler there's nothing to serve
+	rw = httptest.NewRecorder()
+	New(context.Background(), zaptest.NewLogger(t), &mocks.Service{}).createRoutes().ServeHTTP(rw, req)
+	require.Equal(t, http.StatusNotFound, rw.Result().StatusCode)
+}
+
+func Test_ServerCanary(t *testing.T) {
+	tests := []struct {
+		name       string
+		setupMocks func(mockBotService *mocks.Service)
+		method     string
+		path       string
+		payload    string
+		assertions func(t *testing.T, resp http.Response)
+	}{
+		{
+			name: "Success: Get canary",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("Canary").Return(bots.CanaryInfo{Version: "v2", Percent: 10, Channels: []string{"foo"}, Placed: 3})
+			},
+			method: "GET",
+			path:   "/api/v1/canary",
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+				bs, _ := ioutil.ReadAll(resp.Body)
+				require.JSONEq(t, `{"version":"v2","percent":10,"channels":["foo"],"placed":3}`, string(bs))
+			},
+		},
+		{
+			name: "Success: Set canary",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("SetCanary", "v2", 10, []string{"foo"}).Return(nil)
+			},
+			method:  "POST",
+			path:    "/api/v1/canary",
+			payload: `{"version": "v2", "percent": 10, "channels": ["foo"]}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusAccepted, resp.StatusCode)
+			},
+		},
+		{
+			name: "Failure: Invalid percent",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("SetCanary", "v2", 150, []string(nil)).Return(bots.ErrInvalidPercent)
+			},
+			method:  "POST",
+			path:    "/api/v1/canary",
+			payload: `{"version": "v2", "percent": 150}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			},
+		},
+		{
+			name:    "Failure: Missing version",
+			method:  "POST",
+			path:    "/api/v1/canary",
+			payload: `{"percent": 10}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+				bs, _ := ioutil.ReadAll(resp.Body)
+				require.JSONEq(t, `{"error":"missing version in request"}`, string(bs))
+			},
+		},
+		{
+			name: "Success: Promote",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("PromoteCanary").Return(nil)
+			},
+			method: "POST",
+			path:   "/api/v1/canary/promote",
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusAccepted, resp.StatusCode)
+			},
+		},
+		{
+			name: "Failure: Roll back without a canary",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("RollbackCanary").Return(bots.ErrNoCanary)
+			},
+			method: "POST",
+			path:   "/api/v1/canary/rollback",
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusConflict, resp.StatusCode)
+				bs, _ := ioutil.ReadAll(resp.Body)
+				require.JSONEq(t, `{"error":"failed to roll back canary: no canary version set"}`, string(bs))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBotsService := &mocks.Service{}
+			if tt.setupMocks != nil {
+				tt.setupMocks(mockBotsService)
+			}
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.payload))
+			rw := httptest.NewRecorder()
+			server := New(context.Background(), zaptest.NewLogger(t), mockBotsService)
+			server.createRoutes().ServeHTTP(rw, req)
+
+			res := rw.Result()
+			defer res.Body.Close()
+			tt.assertions(t, *res)
+			mockBotsService.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_ServerGroups(t *testing.T) {
+	tests := []struct {
+		name       string
+		setupMocks func(mockBotService *mocks.Service)
+		method     string
+		path       string
+		payload    string
+		assertions func(t *testing.T, resp http.Response)
+	}{
+		{
+			name: "Success: List groups",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("Groups").Return(map[string][]string{"raid": {"bar", "foo"}})
+			},
+			method: "GET",
+			path:   "/api/v1/group",
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+				bs, _ := ioutil.ReadAll(resp.Body)
+				require.JSONEq(t, `{"raid":["bar","foo"]}`, string(bs))
+			},
+		},
+		{
+			name: "Success: Set group",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("SetGroup", "raid", []string{"foo", "bar"}).Return(nil)
+			},
+			method:  "POST",
+			path:    "/api/v1/group/raid",
+			payload: `{"channels": ["foo", "bar"]}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+			},
+		},
+		{
+			name: "Failure: Channel in another group",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("SetGroup", "raid", []string{"foo"}).Return(bots.ErrInGroup)
+			},
+			method:  "POST",
+			path:    "/api/v1/group/raid",
+			payload: `{"channels": ["foo"]}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusConflict, resp.StatusCode)
+				bs, _ := ioutil.ReadAll(resp.Body)
+				require.JSONEq(t, `{"error":"failed to set group: channel is already in another group"}`, string(bs))
+			},
+		},
+		{
+			name: "Failure: Group without channels",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("SetGroup", "raid", []string{}).Return(bots.ErrEmptyGroup)
+			},
+			method:  "POST",
+			path:    "/api/v1/group/raid",
+			payload: `{"channels": []}`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+				bs, _ := ioutil.ReadAll(resp.Body)
+				require.JSONEq(t, `{"error":"failed to set group: group has no channels"}`, string(bs))
+			},
+		},
+		{
+			name:    "Failure: Invalid JSON request",
+			method:  "POST",
+			path:    "/api/v1/group/raid",
+			payload: `{`,
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			},
+		},
+		{
+			name: "Success: Delete group",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("DeleteGroup", "raid").Return(nil)
+			},
+			method: "DELETE",
+			path:   "/api/v1/group/raid",
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+			},
+		},
+		{
+			name: "Failure: Delete missing group",
+			setupMocks: func(mockBotService *mocks.Service) {
+				mockBotService.On("DeleteGroup", "raid").Return(bots.ErrGroupNotExist)
+			},
+			method: "DELETE",
+			path:   "/api/v1/group/raid",
+			assertions: func(t *testing.T, resp http.Response) {
+				require.Equal(t, http.StatusNotFound, resp.StatusCode)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockBotsService := &mocks.Service{}
+			if tt.setupMocks != nil {
+				tt.setupMocks(mockBotsService)
+			}
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.payload))
+			rw := httptest.NewRecorder()
+			server := New(context.Background(), zaptest.NewLogger(t), mockBotsService)
+			server.createRoutes().ServeHTTP(rw, req)
+
+			res := rw.Result()
+			defer res.Body.Close()
+			tt.assertions(t, *res)
+			mockBotsService.AssertExpectations(t)
+		})
+	}
+}
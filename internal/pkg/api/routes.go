@@ -9,6 +9,31 @@ func (s *server) createRoutes() *mux.Router {
 	subrouter.HandleFunc("/join", s.JoinChannel()).Methods("POST")
 	subrouter.HandleFunc("/leave", s.LeaveChannel()).Methods("POST")
 	subrouter.HandleFunc("/bot", s.BotInfo()).Methods("GET")
+	subrouter.HandleFunc("/bot/removable", s.RemovableBots()).Methods("GET")
 	subrouter.HandleFunc("/channel", s.ChannelInfo()).Methods("GET")
+	subrouter.HandleFunc("/move", s.MoveChannel()).Methods("POST")
+	subrouter.HandleFunc("/migration", s.Migrations()).Methods("GET")
+	subrouter.HandleFunc("/pending", s.PendingChannels()).Methods("GET")
+	subrouter.HandleFunc("/unschedulable", s.UnschedulableChannels()).Methods("GET")
+	subrouter.HandleFunc("/failed", s.FailedChannels()).Methods("GET")
+	subrouter.HandleFunc("/exclusion", s.Exclusions()).Methods("GET")
+	subrouter.HandleFunc("/exclusion", s.Exclude()).Methods("POST")
+	subrouter.HandleFunc("/exclusion", s.ClearExclusions()).Methods("DELETE")
+	subrouter.HandleFunc("/bot/{id}/cordon", s.CordonBot()).Methods("POST")
+	subrouter.HandleFunc("/bot/{id}/uncordon", s.UncordonBot()).Methods("POST")
+	subrouter.HandleFunc("/bot/{id}/drain", s.DrainBot()).Methods("POST")
+	subrouter.HandleFunc("/bot/{id}/drain", s.DrainStatus()).Methods("GET")
+	subrouter.HandleFunc("/rebalance/plan", s.RebalancePlan()).Methods("GET")
+	subrouter.HandleFunc("/rebalance", s.Rebalance()).Methods("POST")
+	subrouter.HandleFunc("/group", s.Groups()).Methods("GET")
+	subrouter.HandleFunc("/group/{name}", s.SetGroup()).Methods("POST")
+	subrouter.HandleFunc("/group/{name}", s.DeleteGroup()).Methods("DELETE")
+	subrouter.HandleFunc("/canary", s.Canary()).Methods("GET")
+	subrouter.HandleFunc("/canary", s.SetCanary()).Methods("POST")
+	subrouter.HandleFunc("/canary/promote", s.PromoteCanary()).Methods("POST")
+	subrouter.HandleFunc("/canary/rollback", s.RollbackCanary()).Methods("POST")
+	if s.autoscaler != nil {
+		subrouter.HandleFunc("/scale", s.Scale()).Methods("GET")
+	}
 	return router
 }
@@ -5,21 +5,39 @@ import (
 	"net/http"
 
 	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
+	"github.com/ch629/bot-orchestrator/internal/pkg/scale"
 	"go.uber.org/zap"
 )
 
-type server struct {
-	ctx        context.Context
-	logger     *zap.Logger
-	botService bots.Service
-}
+type (
+	server struct {
+		ctx        context.Context
+		logger     *zap.Logger
+		botService bots.Service
+		autoscaler scale.Autoscaler
+	}
+
+	// Option configures optional parts of the HTTP server
+	Option func(s *server)
+)
 
-func New(ctx context.Context, logger *zap.Logger, botService bots.Service) *server {
-	return &server{
+func New(ctx context.Context, logger *zap.Logger, botService bots.Service, opts ...Option) *server {
+	s := &server{
 		ctx:        ctx,
 		logger:     logger,
 		botService: botService,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithAutoscaler serves the autoscaler's recommendation at /api/v1/scale
+func WithAutoscaler(autoscaler scale.Autoscaler) Option {
+	return func(s *server) {
+		s.autoscaler = autoscaler
+	}
 }
 
 func (s *server) Start(addr string) error {
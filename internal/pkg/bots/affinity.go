@@ -0,0 +1,156 @@
+package bots
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrRulesNotMet is returned when a channel is moved to a bot which doesn't satisfy the channel's placement rules
+var ErrRulesNotMet = errors.New("bot doesn't satisfy channel's placement rules")
+
+// UnschedulableChannel is a struct containing a channel which no bot can run because of its placement rules
+type UnschedulableChannel struct {
+	Channel string `json:"channel"`
+	// MissingReplicas is how many more bots the channel should be running on
+	MissingReplicas int    `json:"missing_replicas"`
+	Reason          string `json:"reason"`
+}
+
+// WithLabels sets the labels a bot registered with, e.g. region, account, version or platform
+func WithLabels(labels map[string]string) BotOption {
+	return func(b *botState) {
+		b.labels = make(map[string]string, len(labels))
+		for k, v := range labels {
+			b.labels[k] = v
+		}
+	}
+}
+
+// WithNodeSelector only places a channel on bots which have every one of the given labels
+func WithNodeSelector(selector map[string]string) ChannelOption {
+	return func(c *channelState) {
+		c.selector = make(map[string]string, len(selector))
+		for k, v := range selector {
+			c.selector[k] = v
+		}
+	}
+}
+
+// WithAntiAffinity places each of a channel's replicas on bots with a different value of the label key, e.g. "host"
+// so that no two replicas share a host
+// Bots without the label can't run the channel
+func WithAntiAffinity(key string) ChannelOption {
+	return func(c *channelState) {
+		c.antiAffinity = append(c.antiAffinity, key)
+	}
+}
+
+// matchesSelector returns whether a bot has every label in the channel's node selector
+func (c *channelState) matchesSelector(bot BotInfo) bool {
+	for k, v := range c.selector {
+		if label, ok := bot.Labels[k]; !ok || label != v {
+			return false
+		}
+	}
+	return true
+}
+
+// spreads returns whether a bot has a different value for each anti-affinity label to every one of the replicas
+func (c *channelState) spreads(bot BotInfo, replicas []BotInfo) bool {
+	for _, key := range c.antiAffinity {
+		value, ok := bot.Labels[key]
+		if !ok {
+			return false
+		}
+		for _, replica := range replicas {
+			if replica.Labels[key] == value {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// allows returns whether a channel's placement rules allow it to run on a bot alongside its other replicas, bots
+// excluded from the channel are never allowed
+func (s *service) allows(channel string, state *channelState, bot BotInfo, replicas []BotInfo) bool {
+	return !s.excluded(channel, bot) && state.matchesSelector(bot) && state.spreads(bot, replicas)
+}
+
+// replicaInfos returns the information of the bots running a channel, excluding the given bot
+func (s *service) replicaInfos(state *channelState, exclude uuid.UUID) []BotInfo {
+	infos := make([]BotInfo, 0, len(state.bots))
+	for _, id := range state.bots {
+		if bot, ok := s.bots[id]; ok && id != exclude {
+			infos = append(infos, bot.BotInfo())
+		}
+	}
+	return infos
+}
+
+// allowsMove returns whether a channel's placement rules allow it to move from one bot to another
+func (s *service) allowsMove(channel string, from uuid.UUID, to BotInfo) bool {
+	state, ok := s.channels[channel]
+	if !ok {
+		return false
+	}
+	return s.allows(channel, state, to, s.replicaInfos(state, from))
+}
+
+// unschedulableReason explains why no connected bot satisfies a channel's placement rules, returns an empty string if
+// at least one does
+func (s *service) unschedulableReason(state *channelState) string {
+	replicas := s.replicaInfos(state, uuid.Nil)
+	var selected bool
+	for _, bot := range s.botInfos() {
+		if state.hasBot(bot.ID) || !state.matchesSelector(bot) {
+			continue
+		}
+		selected = true
+		if state.spreads(bot, replicas) {
+			return ""
+		}
+	}
+	if !selected {
+		return fmt.Sprintf("no bot has labels %s", formatLabels(state.selector))
+	}
+	return fmt.Sprintf("no bot has a different %s to the channel's other replicas", strings.Join(state.antiAffinity, ", "))
+}
+
+// formatLabels formats labels as comma separated key=value pairs, sorted by key
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// UnschedulableChannels returns the channels missing replicas which no connected bot can run because of their
+// placement rules, with the reason why, in alphabetical order
+func (s *service) UnschedulableChannels() []UnschedulableChannel {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.RLock()
+	defer s.chanMux.RUnlock()
+	unschedulable := make([]UnschedulableChannel, 0)
+	for _, channel := range sortedChannels(s.channels) {
+		state := s.channels[channel]
+		if state.missingReplicas() == 0 || (len(state.selector) == 0 && len(state.antiAffinity) == 0) {
+			continue
+		}
+		if reason := s.unschedulableReason(state); reason != "" {
+			unschedulable = append(unschedulable, UnschedulableChannel{
+				Channel:         channel,
+				MissingReplicas: state.missingReplicas(),
+				Reason:          reason,
+			})
+		}
+	}
+	return unschedulable
+}
@@ -0,0 +1,196 @@
+package bots
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidPercent is returned when a canary percentage isn't between 0 and 100
+	ErrInvalidPercent = errors.New("percent must be between 0 and 100")
+	// ErrNoCanary is returned when promoting or rolling back without a canary version set
+	ErrNoCanary = errors.New("no canary version set")
+)
+
+type (
+	// canary is a new bot version being rolled out to a percentage of channels, or an explicit list of them
+	canary struct {
+		version  string
+		percent  int
+		channels map[string]struct{}
+	}
+
+	// CanaryInfo is a struct containing the progress of a canary rollout
+	CanaryInfo struct {
+		Version string `json:"version"`
+		// Percent is the percentage of channels which should run on the canary version
+		Percent int `json:"percent"`
+		// Channels are the channels which should run on the canary version regardless of Percent
+		Channels []string `json:"channels"`
+		// Placed is the number of channel replicas already running on the canary version
+		Placed int `json:"placed"`
+	}
+)
+
+// WithVersion sets the build version a bot reported
+func WithVersion(version string) BotOption {
+	return func(b *botState) {
+		b.version = version
+	}
+}
+
+// SetCanary rolls out a bot version to a percentage of channels and to each of the given channels, moving channels
+// between versions by joining the new bot before the old one leaves
+// An empty version stops any rollout, leaving channels where they are
+// Returns ErrInvalidPercent if percent isn't between 0 and 100
+func (s *service) SetCanary(version string, percent int, channels []string) error {
+	if percent < 0 || percent > 100 {
+		return ErrInvalidPercent
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	s.canary = canary{
+		version:  version,
+		percent:  percent,
+		channels: make(map[string]struct{}, len(channels)),
+	}
+	for _, ch := range channels {
+		s.canary.channels[ch] = struct{}{}
+	}
+	s.logger.Info("setting canary", zap.String("version", version), zap.Int("percent", percent), zap.Strings("channels", channels))
+	s.rollout()
+	return nil
+}
+
+// PromoteCanary moves every channel onto the canary version
+// Returns ErrNoCanary if no canary version is set
+func (s *service) PromoteCanary() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	if s.canary.version == "" {
+		return ErrNoCanary
+	}
+	s.logger.Info("promoting canary", zap.String("version", s.canary.version))
+	s.canary.percent = 100
+	s.rollout()
+	return nil
+}
+
+// RollbackCanary moves every channel off the canary version
+// Returns ErrNoCanary if no canary version is set
+func (s *service) RollbackCanary() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	if s.canary.version == "" {
+		return ErrNoCanary
+	}
+	s.logger.Info("rolling back canary", zap.String("version", s.canary.version))
+	s.canary.percent = 0
+	s.canary.channels = make(map[string]struct{})
+	s.rollout()
+	return nil
+}
+
+// Canary returns the canary version being rolled out and how far it has got
+func (s *service) Canary() CanaryInfo {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.RLock()
+	defer s.chanMux.RUnlock()
+	info := CanaryInfo{
+		Version:  s.canary.version,
+		Percent:  s.canary.percent,
+		Channels: make([]string, 0, len(s.canary.channels)),
+	}
+	for ch := range s.canary.channels {
+		info.Channels = append(info.Channels, ch)
+	}
+	sort.Strings(info.Channels)
+	for _, bot := range s.bots {
+		if s.canary.version != "" && bot.version == s.canary.version {
+			info.Placed += len(bot.channels)
+		}
+	}
+	return info
+}
+
+// wantsCanary returns whether a channel should run on the canary version, channels are picked by hashing their name
+// so the same channels stay on the canary as the percentage grows
+func (c canary) wantsCanary(channel string) bool {
+	if _, ok := c.channels[channel]; ok {
+		return true
+	}
+	return hashKey(channel)%100 < uint64(c.percent)
+}
+
+// versionMatches returns whether a bot is running the version a channel should be on
+func (s *service) versionMatches(channel string, bot BotInfo) bool {
+	if s.canary.version == "" {
+		return true
+	}
+	return (bot.Version == s.canary.version) == s.canary.wantsCanary(channel)
+}
+
+// versionCandidates narrows the candidates for a channel to those running the version it should be on, falling back to
+// any version if none are
+func (s *service) versionCandidates(channel string, candidates []BotInfo) []BotInfo {
+	matching := make([]BotInfo, 0, len(candidates))
+	for _, candidate := range candidates {
+		if s.versionMatches(channel, candidate) {
+			matching = append(matching, candidate)
+		}
+	}
+	if len(matching) == 0 {
+		return candidates
+	}
+	return matching
+}
+
+// rollout moves channel replicas running on the wrong version onto a bot running the right one, one replica per channel
+// at a time and no more than a rebalance batch moving at once
+// Each confirmed move starts another rollout, until every channel which can be is on the right version
+func (s *service) rollout() {
+	for _, channel := range sortedChannels(s.channels) {
+		if len(s.migrations) >= s.rebalanceBatchSize {
+			return
+		}
+		if _, ok := s.migrations[channel]; ok {
+			continue
+		}
+		state := s.channels[channel]
+		for _, id := range state.bots {
+			bot, ok := s.bots[id]
+			if !ok || state.pinnedTo(id) || s.versionMatches(channel, bot.BotInfo()) {
+				continue
+			}
+			target, err := s.placeChannel(channel, state)
+			if err != nil || !s.versionMatches(channel, target.BotInfo()) {
+				// Nowhere better to go yet
+				break
+			}
+			if err := s.moveChannel(Move{
+				Channel: channel,
+				From:    id,
+				To:      target.id,
+			}); err != nil {
+				s.logger.Warn("failed to move channel to new version", zap.String("channel", channel), zap.Error(err))
+			}
+			break
+		}
+	}
+}
+
+// allowsRebalance returns whether a rebalance can move a channel from one bot to another, keeping it on the right
+// version
+func (s *service) allowsRebalance(channel string, from uuid.UUID, to BotInfo) bool {
+	return s.allowsMove(channel, from, to) && s.versionMatches(channel, to)
+}
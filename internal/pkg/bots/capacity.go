@@ -0,0 +1,23 @@
+package bots
+
+import "errors"
+
+// ErrAtCapacity is returned when a bot is already running its maximum number of channels
+var ErrAtCapacity = errors.New("bot is at capacity")
+
+// BotOption configures an individual bot as it joins
+type BotOption func(b *botState)
+
+// WithMaxChannels limits the number of channels a bot can be placed in, 0 means no limit
+func WithMaxChannels(maxChannels int) BotOption {
+	return func(b *botState) {
+		if maxChannels >= 0 {
+			b.maxChannels = maxChannels
+		}
+	}
+}
+
+// hasCapacity returns whether a bot can be placed in another channel
+func (b BotInfo) hasCapacity() bool {
+	return b.MaxChannels == 0 || len(b.Channels) < b.MaxChannels
+}
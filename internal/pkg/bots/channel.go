@@ -0,0 +1,102 @@
+package bots
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type (
+	// ChannelOption configures how an individual channel is placed
+	ChannelOption func(c *channelState)
+
+	channelState struct {
+		// bots are the IDs of the bots currently in the channel
+		bots []uuid.UUID
+		// replicas is the number of distinct bots the channel should be running on
+		replicas int
+		// pinned is the bot which must always run one of the channel's replicas, uuid.Nil if not pinned
+		pinned uuid.UUID
+		// pinLostAt is when the channel started waiting for its pinned bot
+		pinLostAt time.Time
+		// selector is the labels a bot must have to run the channel
+		selector map[string]string
+		// antiAffinity are the label keys each replica must have a different value for
+		antiAffinity []string
+		// priority is how important the channel is, higher priority channels are placed first and can evict lower ones
+		priority int
+		// failures is how many bots in a row couldn't join the channel, lastFailure is why the last one couldn't
+		failures    int
+		lastFailure *joinFailure
+		// failedBots couldn't join the channel since it was last joined, other bots are tried first
+		failedBots map[uuid.UUID]struct{}
+		// retryAt is when the channel can be placed again after failing, retryTimer places it then
+		retryAt    time.Time
+		retryTimer *time.Timer
+		// givenUp channels aren't placed again because bots couldn't join them
+		givenUp bool
+	}
+)
+
+// WithReplicas overrides the service's replication factor for a single channel
+func WithReplicas(replicas int) ChannelOption {
+	return func(c *channelState) {
+		c.replicas = replicas
+	}
+}
+
+// hasBot returns whether a bot is one of the channel's replicas
+func (c *channelState) hasBot(id uuid.UUID) bool {
+	for _, botID := range c.bots {
+		if botID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// removeBot removes a bot from the channel's replicas
+func (c *channelState) removeBot(id uuid.UUID) {
+	ids := make([]uuid.UUID, 0, len(c.bots))
+	for _, botID := range c.bots {
+		if botID != id {
+			ids = append(ids, botID)
+		}
+	}
+	c.bots = ids
+}
+
+// joinSucceeded resets the channel's failures once a bot has joined it
+func (c *channelState) joinSucceeded() {
+	c.failures = 0
+	c.retryAt = time.Time{}
+	c.failedBots = nil
+}
+
+// untried narrows the candidates to the bots which haven't failed to join the channel, falling back to every candidate
+// if they all have
+func (c *channelState) untried(candidates []BotInfo) []BotInfo {
+	untried := make([]BotInfo, 0, len(candidates))
+	for _, candidate := range candidates {
+		if _, failed := c.failedBots[candidate.ID]; !failed {
+			untried = append(untried, candidate)
+		}
+	}
+	if len(untried) == 0 {
+		return candidates
+	}
+	return untried
+}
+
+// backingOff returns whether the channel is waiting to be placed again after failing
+func (c *channelState) backingOff() bool {
+	return time.Now().Before(c.retryAt)
+}
+
+// missingReplicas returns how many more bots need to join the channel to reach its replication factor
+func (c *channelState) missingReplicas() int {
+	if missing := c.replicas - len(c.bots); missing > 0 {
+		return missing
+	}
+	return 0
+}
@@ -0,0 +1,294 @@
+package bots
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ch629/bot-orchestrator/internal/pkg/proto"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrCommandNotExist is returned when a bot acknowledges a command which isn't waiting to be acknowledged
+	ErrCommandNotExist = errors.New("command does not exist")
+	// errAckTimeout is why a command failed when the bot didn't acknowledge it in time
+	errAckTimeout = errors.New("timed out waiting for ack")
+)
+
+// command is a JOIN or LEAVE sent to a bot which is waiting to be sent or acknowledged
+type command struct {
+	id      string
+	bot     uuid.UUID
+	join    bool
+	channel string
+	// attempts is how many times the command has been sent
+	attempts int
+	// timer is nil while the command is queued to be sent
+	timer *time.Timer
+}
+
+// WithAckTimeout sets how long a bot has to acknowledge a JOIN or LEAVE before it's treated as failed
+func WithAckTimeout(timeout time.Duration) Option {
+	return func(s *service) {
+		s.ackTimeout = timeout
+	}
+}
+
+// WithCommandRetries sets how many times a JOIN or LEAVE is sent before giving up on it, waiting backoff before the
+// first retry and doubling it after each one
+// A channel whose JOIN is given up on is reassigned to a different bot, attempts below 1 are ignored
+func WithCommandRetries(attempts int, backoff time.Duration) Option {
+	return func(s *service) {
+		if attempts >= 1 {
+			s.commandAttempts = attempts
+		}
+		s.commandBackoff = backoff
+	}
+}
+
+// WithUnacknowledgedCommands is for bots which can't acknowledge commands, e.g. bots using JoinStream, their JOINs and
+// LEAVEs are done once they've been sent
+func WithUnacknowledgedCommands() BotOption {
+	return func(b *botState) {
+		b.unackedCommands = true
+	}
+}
+
+// joinChannel assigns a channel to a bot and sends it a JOIN, the bot has only joined once it acknowledges the JOIN
+func (s *service) joinChannel(bot *botState, channel string) {
+	bot.assign(channel)
+	s.cancelCommands(bot.id, channel)
+	s.sendCommand(bot, &command{
+		id:      uuid.NewString(),
+		bot:     bot.id,
+		join:    true,
+		channel: channel,
+	})
+}
+
+// leaveChannel takes a channel away from a bot and sends it a LEAVE, cancelling any JOIN still waiting for an ack
+// Returns ErrNotInChannel if the bot isn't in the channel
+func (s *service) leaveChannel(bot *botState, channel string) error {
+	if !bot.unassign(channel) {
+		return ErrNotInChannel
+	}
+	s.cancelCommands(bot.id, channel)
+	s.sendCommand(bot, &command{
+		id:      uuid.NewString(),
+		bot:     bot.id,
+		channel: channel,
+	})
+	return nil
+}
+
+// sendCommand sends a command to its bot and waits for the bot to acknowledge it, commands queued by the bot's client
+// wait for it to send them before waiting for the ack
+func (s *service) sendCommand(bot *botState, cmd *command) {
+	cmd.attempts++
+	cmd.timer = nil
+	logger := bot.logger.With(zap.String("channel", cmd.channel), zap.String("command_id", cmd.id), zap.Int("attempt", cmd.attempts))
+	var err error
+	if cmd.join {
+		logger.Info("bot joining channel")
+		err = bot.client.SendJoinChannel(cmd.id, cmd.channel)
+	} else {
+		logger.Info("bot leaving channel")
+		err = bot.client.SendLeaveChannel(cmd.id, cmd.channel)
+	}
+	s.commands[cmd.id] = cmd
+	if _, queued := bot.client.(proto.QueuedClient); queued && err == nil {
+		// The ack timeout starts once the client has sent the command
+		return
+	}
+	if err != nil || bot.unackedCommands {
+		// Finish after the caller has, so the channel isn't reassigned part way through being placed
+		cmd.timer = time.AfterFunc(0, func() {
+			s.mux.Lock()
+			defer s.mux.Unlock()
+			s.chanMux.Lock()
+			defer s.chanMux.Unlock()
+			if s.commands[cmd.id] == cmd {
+				s.sent(cmd, err)
+			}
+		})
+		return
+	}
+	s.waitForAck(cmd)
+}
+
+// commandSent is called by a bot's queued client once it has sent a command, err is why it couldn't be sent
+func (s *service) commandSent(id uuid.UUID, commandID string, err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	cmd, ok := s.commands[commandID]
+	if !ok || cmd.bot != id || cmd.timer != nil {
+		return
+	}
+	s.sent(cmd, err)
+}
+
+// sent fails a command which couldn't be sent, otherwise it waits for the bot to acknowledge it, or it's done if the
+// bot can't acknowledge it
+func (s *service) sent(cmd *command, err error) {
+	if err != nil {
+		s.commandFailed(cmd, err)
+		return
+	}
+	bot, ok := s.bots[cmd.bot]
+	if !ok || !bot.unackedCommands {
+		s.waitForAck(cmd)
+		return
+	}
+	delete(s.commands, cmd.id)
+	if !cmd.join {
+		return
+	}
+	if err := s.joined(cmd.bot, cmd.channel); err != nil {
+		bot.logger.Warn("failed to complete join", zap.String("channel", cmd.channel), zap.Error(err))
+	}
+}
+
+// waitForAck fails a command if the bot doesn't acknowledge it within the ack timeout
+func (s *service) waitForAck(cmd *command) {
+	cmd.timer = time.AfterFunc(s.ackTimeout, func() {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		s.chanMux.Lock()
+		defer s.chanMux.Unlock()
+		if s.commands[cmd.id] == cmd {
+			s.commandFailed(cmd, errAckTimeout)
+		}
+	})
+}
+
+// stop stops waiting for the command to be sent again or acknowledged
+func (c *command) stop() {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+}
+
+// commandFailed sends a command again after a backoff, or gives up on it once it has run out of attempts or the
+// bot can't carry it out
+func (s *service) commandFailed(cmd *command, err error) {
+	logger := s.logger.With(
+		zap.String("bot_id", cmd.bot.String()),
+		zap.String("channel", cmd.channel),
+		zap.String("command_id", cmd.id),
+		zap.Bool("join", cmd.join),
+		zap.Int("attempt", cmd.attempts),
+		zap.Error(err),
+	)
+	if cmd.attempts < s.commandAttempts && retryable(err) {
+		backoff := s.commandBackoff << (cmd.attempts - 1)
+		logger.Warn("command failed, retrying", zap.Duration("backoff", backoff))
+		cmd.timer = time.AfterFunc(backoff, func() {
+			s.mux.Lock()
+			defer s.mux.Unlock()
+			s.chanMux.Lock()
+			defer s.chanMux.Unlock()
+			if s.commands[cmd.id] != cmd {
+				return
+			}
+			bot, ok := s.bots[cmd.bot]
+			if !ok {
+				delete(s.commands, cmd.id)
+				return
+			}
+			s.sendCommand(bot, cmd)
+		})
+		return
+	}
+	delete(s.commands, cmd.id)
+	logger.Warn("command failed, giving up", zap.String("reason", failureReason(err)))
+	if cmd.join {
+		s.joinFailed(cmd.bot, cmd.channel, err)
+	}
+}
+
+// reassignChannel moves a channel off a bot which failed to join it or is excluded from it onto a different bot
+func (s *service) reassignChannel(id uuid.UUID, channel string) {
+	state, ok := s.channels[channel]
+	if !ok || !state.hasBot(id) {
+		return
+	}
+	if mig, ok := s.migrations[channel]; ok && mig.to == id {
+		// The channel is still running on the bot it was moving from
+		s.rollbackMigration(mig)
+		return
+	}
+	s.logger.Warn("reassigning channel", zap.String("channel", channel), zap.String("bot_id", id.String()))
+	state.removeBot(id)
+	if bot, ok := s.bots[id]; ok {
+		// Make sure the bot isn't left half joined
+		if err := s.leaveChannel(bot, channel); err != nil {
+			bot.logger.Warn("failed to leave channel", zap.String("channel", channel), zap.Error(err))
+		}
+	}
+	s.distributeChannels()
+}
+
+// cancelCommands stops waiting for a bot to acknowledge any commands for a channel
+func (s *service) cancelCommands(id uuid.UUID, channel string) {
+	for cmdID, cmd := range s.commands {
+		if cmd.bot == id && cmd.channel == channel {
+			cmd.stop()
+			delete(s.commands, cmdID)
+		}
+	}
+}
+
+// cancelBotCommands stops waiting for a bot to acknowledge any commands
+func (s *service) cancelBotCommands(id uuid.UUID) {
+	for cmdID, cmd := range s.commands {
+		if cmd.bot == id {
+			cmd.stop()
+			delete(s.commands, cmdID)
+		}
+	}
+}
+
+// joiningChannels returns the channels a bot has been sent a JOIN for which it hasn't acknowledged yet, in alphabetical
+// order
+func (s *service) joiningChannels(id uuid.UUID) []string {
+	channels := make([]string, 0)
+	for _, cmd := range s.commands {
+		if cmd.bot == id && cmd.join {
+			channels = append(channels, cmd.channel)
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// AckCommand is called by a bot once it has carried out a command, ackErr is why the command failed or nil if it
+// succeeded
+// Failed commands are sent again, and a JOIN only counts as joined once it has succeeded
+// Returns ErrBotNotExist if the bot doesn't exist, or ErrCommandNotExist if the command isn't waiting for an ack
+func (s *service) AckCommand(id uuid.UUID, commandID string, ackErr error) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, ok := s.bots[id]; !ok {
+		return ErrBotNotExist
+	}
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	cmd, ok := s.commands[commandID]
+	if !ok || cmd.bot != id {
+		return ErrCommandNotExist
+	}
+	cmd.stop()
+	if ackErr != nil {
+		s.commandFailed(cmd, ackErr)
+		return nil
+	}
+	delete(s.commands, commandID)
+	if !cmd.join {
+		return nil
+	}
+	return s.joined(id, cmd.channel)
+}
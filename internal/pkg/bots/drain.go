@@ -0,0 +1,198 @@
+package bots
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// DrainStatus is a struct containing the progress of a bot being drained
+type DrainStatus struct {
+	ID       uuid.UUID `json:"id"`
+	Draining bool      `json:"draining"`
+	// Remaining is the number of channels the bot is still in, excluding channels pinned to it
+	Remaining int  `json:"remaining"`
+	Complete  bool `json:"complete"`
+}
+
+// WithDrainRetryInterval sets how long to wait before trying to move a draining bot's channels again when no other bot
+// can take them
+func WithDrainRetryInterval(interval time.Duration) Option {
+	return func(s *service) {
+		s.drainRetryInterval = interval
+	}
+}
+
+// Cordon marks a bot as unschedulable, so no new channels are placed on it
+// Returns ErrBotNotExist if the bot doesn't exist
+func (s *service) Cordon(id uuid.UUID) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	bot, ok := s.bots[id]
+	if !ok {
+		return ErrBotNotExist
+	}
+	bot.logger.Info("cordoning bot")
+	bot.cordoned = true
+	return nil
+}
+
+// Uncordon marks a bot as schedulable again, stopping any drain in progress
+// Returns ErrBotNotExist if the bot doesn't exist
+func (s *service) Uncordon(id uuid.UUID) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	bot, ok := s.bots[id]
+	if !ok {
+		return ErrBotNotExist
+	}
+	bot.logger.Info("uncordoning bot")
+	bot.cordoned = false
+	bot.draining = false
+	bot.stopDrainRetry()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	s.distributeChannels()
+	return nil
+}
+
+// Drain cordons a bot and moves its channels to other bots one at a time, each joining before the bot leaves, channels
+// pinned to the bot stay on it
+// Returns ErrBotNotExist if the bot doesn't exist
+func (s *service) Drain(id uuid.UUID) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	bot, ok := s.bots[id]
+	if !ok {
+		return ErrBotNotExist
+	}
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	if bot.draining {
+		return nil
+	}
+	bot.logger.Info("draining bot")
+	bot.cordoned = true
+	bot.draining = true
+	s.drainNext(id)
+	return nil
+}
+
+// DrainStatus returns how far through draining a bot is
+// Returns ErrBotNotExist if the bot doesn't exist
+func (s *service) DrainStatus(id uuid.UUID) (DrainStatus, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	bot, ok := s.bots[id]
+	if !ok {
+		return DrainStatus{}, ErrBotNotExist
+	}
+	s.chanMux.RLock()
+	defer s.chanMux.RUnlock()
+	remaining := len(s.drainableChannels(bot))
+	return DrainStatus{
+		ID:        id,
+		Draining:  bot.draining,
+		Remaining: remaining,
+		Complete:  bot.draining && remaining == 0,
+	}, nil
+}
+
+// drainNext starts moving the next channel off a draining bot, unless one is already moving
+func (s *service) drainNext(id uuid.UUID) {
+	bot, ok := s.bots[id]
+	if !ok || !bot.draining {
+		return
+	}
+	bot.stopDrainRetry()
+	for _, mig := range s.migrations {
+		if mig.from == id {
+			// Wait for the current move to finish first
+			return
+		}
+	}
+	channels := s.drainableChannels(bot)
+	for _, channel := range channels {
+		if _, ok := s.channels[channel]; !ok {
+			// Not tracked any more, so there's nothing to move
+			if err := s.leaveChannel(bot, channel); err != nil {
+				bot.logger.Warn("failed to leave channel", zap.String("channel", channel), zap.Error(err))
+			}
+			continue
+		}
+		err := s.drainChannel(bot, channel)
+		if err == nil {
+			return
+		}
+		bot.logger.Warn("failed to move channel off draining bot", zap.String("channel", channel), zap.Error(err))
+	}
+	if len(s.drainableChannels(bot)) == 0 {
+		bot.logger.Info("bot drained")
+		return
+	}
+	// Nothing could be moved, try again later in case more bots join
+	var retry *time.Timer
+	retry = time.AfterFunc(s.drainRetryInterval, func() {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		s.chanMux.Lock()
+		defer s.chanMux.Unlock()
+		if bot.drainRetry != retry {
+			// Already stopped or replaced
+			return
+		}
+		bot.drainRetry = nil
+		s.drainNext(id)
+	})
+	bot.drainRetry = retry
+}
+
+// stopDrainRetry stops any retry pending for a draining bot
+func (b *botState) stopDrainRetry() {
+	if b.drainRetry != nil {
+		b.drainRetry.Stop()
+		b.drainRetry = nil
+	}
+}
+
+// drainableChannels returns the channels which need moving off a draining bot in alphabetical order, channels pinned
+// to the bot stay on it
+func (s *service) drainableChannels(bot *botState) []string {
+	channels := make([]string, 0)
+	for _, channel := range bot.BotInfo().Channels {
+		if state, ok := s.channels[channel]; ok && state.pinnedTo(bot.id) {
+			continue
+		}
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// drainChannel moves a single channel off a draining bot onto a bot picked by the placement strategy
+func (s *service) drainChannel(bot *botState, channel string) error {
+	state := s.channels[channel]
+	if _, ok := s.migrations[channel]; ok {
+		return ErrMigrating
+	}
+	target, err := s.placeChannel(channel, state)
+	if err != nil {
+		return err
+	}
+	if err := s.moveChannel(Move{
+		Channel: channel,
+		From:    bot.id,
+		To:      target.id,
+	}); err != nil {
+		return fmt.Errorf("moveChannel: %w", err)
+	}
+	return nil
+}
+
+// isSchedulable returns whether new channels can be placed on a bot
+func (b BotInfo) isSchedulable() bool {
+	return !b.Cordoned && b.hasCapacity()
+}
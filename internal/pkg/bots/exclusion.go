@@ -0,0 +1,190 @@
+package bots
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidExclusion is returned when an exclusion doesn't have a channel, or doesn't have exactly one of a bot or
+// an account
+var ErrInvalidExclusion = errors.New("exclusion must have a channel and one of a bot or an account")
+
+type (
+	// exclusionKey is who is excluded from a channel, either a bot or every bot using an account
+	exclusionKey struct {
+		channel string
+		bot     uuid.UUID
+		account string
+	}
+
+	exclusion struct {
+		reason    string
+		expiresAt time.Time
+		timer     *time.Timer
+	}
+
+	// Exclusion stops a channel being placed on a bot, or on every bot using an account, until it expires
+	Exclusion struct {
+		Channel string `json:"channel"`
+		// BotID or Account is who is excluded from the channel, only one is set
+		BotID   *uuid.UUID `json:"bot_id,omitempty"`
+		Account string     `json:"account,omitempty"`
+		// Reason is why they're excluded, e.g. banned
+		Reason    string    `json:"reason"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+)
+
+// WithExclusionTTL sets how long a bot or account is excluded from a channel after being banned from it, or when an
+// exclusion is added without an expiry
+func WithExclusionTTL(ttl time.Duration) Option {
+	return func(s *service) {
+		s.exclusionTTL = ttl
+	}
+}
+
+// excluded returns whether a bot, or the account it uses, is excluded from a channel
+func (s *service) excluded(channel string, bot BotInfo) bool {
+	if _, ok := s.exclusions[exclusionKey{channel: channel, bot: bot.ID}]; ok {
+		return true
+	}
+	account, ok := bot.Labels["account"]
+	if !ok {
+		return false
+	}
+	_, ok = s.exclusions[exclusionKey{channel: channel, account: account}]
+	return ok
+}
+
+// exclude stops a channel being placed on a bot or account until expiresAt, replacing any existing exclusion
+func (s *service) exclude(key exclusionKey, reason string, expiresAt time.Time) {
+	if existing, ok := s.exclusions[key]; ok {
+		existing.timer.Stop()
+	}
+	e := &exclusion{
+		reason:    reason,
+		expiresAt: expiresAt,
+	}
+	e.timer = time.AfterFunc(time.Until(expiresAt), func() {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		s.chanMux.Lock()
+		defer s.chanMux.Unlock()
+		if s.exclusions[key] != e {
+			return
+		}
+		delete(s.exclusions, key)
+		s.logger.Info("exclusion expired", key.fields()...)
+		// The channel may be able to run on the bot again
+		s.distributeChannels()
+	})
+	s.exclusions[key] = e
+	s.logger.Info("excluding from channel", append(key.fields(), zap.String("reason", reason), zap.Time("expires_at", expiresAt))...)
+}
+
+// excludeBanned excludes the account a bot uses from a channel it's banned from, or just the bot if it has no account
+func (s *service) excludeBanned(id uuid.UUID, channel string) {
+	key := exclusionKey{channel: channel, bot: id}
+	if bot, ok := s.bots[id]; ok && bot.labels["account"] != "" {
+		key = exclusionKey{channel: channel, account: bot.labels["account"]}
+	}
+	s.exclude(key, failureReason(ErrBanned), time.Now().Add(s.exclusionTTL))
+}
+
+// Exclude stops a channel being placed on a bot or account, moving the channel off any bot it's excluded from
+// Exclusions without an expiry last for the exclusion TTL
+// Returns ErrInvalidExclusion if the exclusion doesn't have a channel, or doesn't have exactly one of a bot or an
+// account
+func (s *service) Exclude(e Exclusion) error {
+	if e.Channel == "" || (e.BotID == nil) == (e.Account == "") {
+		return ErrInvalidExclusion
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	key := exclusionKey{channel: e.Channel, account: e.Account}
+	if e.BotID != nil {
+		key.bot = *e.BotID
+	}
+	expiresAt := e.ExpiresAt
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(s.exclusionTTL)
+	}
+	s.exclude(key, e.Reason, expiresAt)
+	state, ok := s.channels[e.Channel]
+	if !ok {
+		return nil
+	}
+	for _, id := range state.bots {
+		if bot, ok := s.bots[id]; ok && s.excluded(e.Channel, bot.BotInfo()) {
+			s.reassignChannel(id, e.Channel)
+		}
+	}
+	return nil
+}
+
+// Exclusions returns the bots and accounts excluded from channels, ordered by channel
+func (s *service) Exclusions() []Exclusion {
+	s.chanMux.RLock()
+	defer s.chanMux.RUnlock()
+	exclusions := make([]Exclusion, 0, len(s.exclusions))
+	for key, e := range s.exclusions {
+		exclusion := Exclusion{
+			Channel:   key.channel,
+			Account:   key.account,
+			Reason:    e.reason,
+			ExpiresAt: e.expiresAt,
+		}
+		if key.bot != uuid.Nil {
+			id := key.bot
+			exclusion.BotID = &id
+		}
+		exclusions = append(exclusions, exclusion)
+	}
+	sort.Slice(exclusions, func(i, j int) bool {
+		if exclusions[i].Channel != exclusions[j].Channel {
+			return exclusions[i].Channel < exclusions[j].Channel
+		}
+		return exclusions[i].ExpiresAt.Before(exclusions[j].ExpiresAt)
+	})
+	return exclusions
+}
+
+// ClearExclusions removes the exclusions matching each of the channel, bot and account set in filter, every exclusion
+// if none are set, returning how many were removed
+func (s *service) ClearExclusions(filter Exclusion) int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	var cleared int
+	for key, e := range s.exclusions {
+		if (filter.Channel != "" && key.channel != filter.Channel) ||
+			(filter.BotID != nil && key.bot != *filter.BotID) ||
+			(filter.Account != "" && key.account != filter.Account) {
+			continue
+		}
+		e.timer.Stop()
+		delete(s.exclusions, key)
+		cleared++
+	}
+	if cleared > 0 {
+		s.logger.Info("cleared exclusions", zap.Int("count", cleared))
+		s.distributeChannels()
+	}
+	return cleared
+}
+
+// fields describes who is excluded from which channel for logging
+func (k exclusionKey) fields() []zap.Field {
+	fields := []zap.Field{zap.String("channel", k.channel)}
+	if k.bot != uuid.Nil {
+		return append(fields, zap.String("bot_id", k.bot.String()))
+	}
+	return append(fields, zap.String("account", k.account))
+}
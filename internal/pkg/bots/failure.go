@@ -0,0 +1,173 @@
+package bots
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrBanned is reported by a bot whose account is banned from a channel, other bots may still be able to join it
+	ErrBanned = errors.New("banned from channel")
+	// ErrChannelNotFound is reported by a bot when a channel doesn't exist, so no bot can join it
+	ErrChannelNotFound = errors.New("channel not found")
+	// ErrChannelSuspended is reported by a bot when a channel has been suspended, so no bot can join it
+	ErrChannelSuspended = errors.New("channel suspended")
+)
+
+type (
+	// joinFailure is why a bot couldn't join a channel
+	joinFailure struct {
+		reason   string
+		err      error
+		failedAt time.Time
+	}
+
+	// FailedChannel is a channel bots couldn't join, it isn't placed again until it's left and joined again
+	FailedChannel struct {
+		Channel string `json:"channel"`
+		// Reason is why the last JOIN failed, one of banned, channel_not_found, channel_suspended, timeout or error
+		Reason string `json:"reason"`
+		Error  string `json:"error"`
+		// Attempts is how many bots in a row couldn't join the channel
+		Attempts int       `json:"attempts"`
+		FailedAt time.Time `json:"failed_at"`
+	}
+)
+
+// WithChannelRetries sets how many bots in a row can fail to join a channel before it's given up on, the first retry
+// on another bot is immediate then each one waits backoff, doubling it after each one
+// Attempts below 1 are ignored
+func WithChannelRetries(attempts int, backoff time.Duration) Option {
+	return func(s *service) {
+		if attempts >= 1 {
+			s.channelAttempts = attempts
+		}
+		s.channelBackoff = backoff
+	}
+}
+
+// failureReason classifies why a JOIN failed
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrBanned):
+		return "banned"
+	case errors.Is(err, ErrChannelNotFound):
+		return "channel_not_found"
+	case errors.Is(err, ErrChannelSuspended):
+		return "channel_suspended"
+	case errors.Is(err, errAckTimeout):
+		return "timeout"
+	default:
+		return "error"
+	}
+}
+
+// retryable returns whether a failed command is worth sending to the same bot again
+func retryable(err error) bool {
+	return !errors.Is(err, ErrBanned) && !unjoinable(err)
+}
+
+// unjoinable returns whether a JOIN failed because no bot can join the channel
+func unjoinable(err error) bool {
+	return errors.Is(err, ErrChannelNotFound) || errors.Is(err, ErrChannelSuspended)
+}
+
+// joinFailed records that a bot gave up joining a channel, excluding it if it's banned, then moves the channel onto
+// another bot after a backoff, or gives up on it if no bot can join it
+func (s *service) joinFailed(id uuid.UUID, channel string, err error) {
+	state, ok := s.channels[channel]
+	if !ok || !state.hasBot(id) {
+		return
+	}
+	if errors.Is(err, ErrBanned) {
+		s.excludeBanned(id, channel)
+	}
+	if state.failedBots == nil {
+		state.failedBots = make(map[uuid.UUID]struct{})
+	}
+	state.failedBots[id] = struct{}{}
+	state.failures++
+	state.lastFailure = &joinFailure{
+		reason:   failureReason(err),
+		err:      err,
+		failedAt: time.Now(),
+	}
+	if unjoinable(err) || state.failures >= s.channelAttempts {
+		s.giveUpChannel(id, channel, state)
+	} else if state.failures > 1 {
+		s.backOffChannel(channel, state)
+	}
+	s.reassignChannel(id, channel)
+}
+
+// giveUpChannel stops placing a channel bots can't join, if it can't be joined at all the other bots are taken out
+// of it too
+func (s *service) giveUpChannel(id uuid.UUID, channel string, state *channelState) {
+	s.logger.Warn("giving up on channel",
+		zap.String("channel", channel),
+		zap.String("reason", state.lastFailure.reason),
+		zap.Int("attempts", state.failures),
+		zap.Error(state.lastFailure.err),
+	)
+	state.givenUp = true
+	s.dequeue(channel)
+	if !unjoinable(state.lastFailure.err) {
+		return
+	}
+	s.cancelMigration(channel)
+	for _, botID := range state.bots {
+		if botID == id {
+			continue
+		}
+		state.removeBot(botID)
+		if bot, ok := s.bots[botID]; ok {
+			if err := s.leaveChannel(bot, channel); err != nil {
+				bot.logger.Warn("failed to leave channel", zap.String("channel", channel), zap.Error(err))
+			}
+		}
+	}
+}
+
+// backOffChannel stops a channel being placed again until its backoff has passed
+func (s *service) backOffChannel(channel string, state *channelState) {
+	backoff := s.channelBackoff << (state.failures - 2)
+	s.logger.Info("backing off channel", zap.String("channel", channel), zap.Duration("backoff", backoff))
+	state.retryAt = time.Now().Add(backoff)
+	if state.retryTimer != nil {
+		state.retryTimer.Stop()
+	}
+	state.retryTimer = time.AfterFunc(backoff, func() {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		s.chanMux.Lock()
+		defer s.chanMux.Unlock()
+		if s.channels[channel] == state {
+			s.distributeChannels()
+		}
+	})
+}
+
+// FailedChannels returns the channels which have been given up on because bots couldn't join them, in alphabetical
+// order
+func (s *service) FailedChannels() []FailedChannel {
+	s.chanMux.RLock()
+	defer s.chanMux.RUnlock()
+	failed := make([]FailedChannel, 0)
+	for _, channel := range sortedChannels(s.channels) {
+		state := s.channels[channel]
+		if !state.givenUp {
+			continue
+		}
+		failed = append(failed, FailedChannel{
+			Channel:  channel,
+			Reason:   state.lastFailure.reason,
+			Error:    state.lastFailure.err.Error(),
+			Attempts: state.failures,
+			FailedAt: state.lastFailure.failedAt,
+		})
+	}
+	return failed
+}
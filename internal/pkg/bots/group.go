@@ -0,0 +1,193 @@
+package bots
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrGroupNotExist is returned when a group doesn't exist
+	ErrGroupNotExist = errors.New("group does not exist")
+	// ErrInGroup is returned when a channel is added to a group while it's already in another one
+	ErrInGroup = errors.New("channel is already in another group")
+	// ErrEmptyGroup is returned when a group is set without any channels
+	ErrEmptyGroup = errors.New("group has no channels")
+)
+
+// SetGroup sets the channels in a named group, which are always placed on the same bots as each other
+// Channels don't need to have been joined yet, any already running apart are moved together
+// Returns ErrEmptyGroup if there are no channels, or ErrInGroup if one of the channels is in another group
+func (s *service) SetGroup(name string, channels []string) error {
+	if len(channels) == 0 {
+		return ErrEmptyGroup
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	for _, ch := range channels {
+		if group, ok := s.groupOf(ch); ok && group != name {
+			return ErrInGroup
+		}
+	}
+	members := make([]string, len(channels))
+	copy(members, channels)
+	sort.Strings(members)
+	s.groups[name] = members
+	s.logger.Info("setting group", zap.String("group", name), zap.Strings("channels", members))
+	s.colocateGroups()
+	return nil
+}
+
+// DeleteGroup stops placing a group's channels together, leaving them where they are
+// Returns ErrGroupNotExist if the group doesn't exist
+func (s *service) DeleteGroup(name string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	if _, ok := s.groups[name]; !ok {
+		return ErrGroupNotExist
+	}
+	delete(s.groups, name)
+	return nil
+}
+
+// Groups returns the channels in each group
+func (s *service) Groups() map[string][]string {
+	s.chanMux.RLock()
+	defer s.chanMux.RUnlock()
+	groups := make(map[string][]string, len(s.groups))
+	for name, members := range s.groups {
+		groups[name] = make([]string, len(members))
+		copy(groups[name], members)
+	}
+	return groups
+}
+
+// groupOf returns the group a channel is in
+func (s *service) groupOf(channel string) (string, bool) {
+	for name, members := range s.groups {
+		for _, member := range members {
+			if member == channel {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// sameGroup returns whether two channels are in the same group
+func (s *service) sameGroup(channel, other string) bool {
+	group, ok := s.groupOf(channel)
+	if !ok {
+		return false
+	}
+	otherGroup, ok := s.groupOf(other)
+	return ok && group == otherGroup
+}
+
+// groupHosts returns the bots running any of the other channels in a channel's group
+func (s *service) groupHosts(channel string) map[uuid.UUID]struct{} {
+	hosts := make(map[uuid.UUID]struct{})
+	name, ok := s.groupOf(channel)
+	if !ok {
+		return hosts
+	}
+	for _, member := range s.groups[name] {
+		if state, ok := s.channels[member]; ok && member != channel {
+			for _, id := range state.bots {
+				hosts[id] = struct{}{}
+			}
+		}
+	}
+	return hosts
+}
+
+// groupCandidates narrows the candidates for a grouped channel to the bots already running the rest of its group
+// If the group isn't running anywhere, or only on cordoned bots, bots with room for the whole group are preferred
+func (s *service) groupCandidates(channel string, candidates []BotInfo) []BotInfo {
+	name, ok := s.groupOf(channel)
+	if !ok {
+		return candidates
+	}
+	hosts := s.groupHosts(channel)
+	together := make([]BotInfo, 0, len(candidates))
+	for _, candidate := range candidates {
+		if _, ok := hosts[candidate.ID]; ok {
+			together = append(together, candidate)
+		}
+	}
+	if len(together) > 0 {
+		return together
+	}
+	for id := range hosts {
+		if bot, ok := s.bots[id]; ok && !bot.cordoned {
+			// Wait for room alongside the rest of the group rather than splitting it up
+			return together
+		}
+	}
+	need := 0
+	for _, member := range s.groups[name] {
+		if _, ok := s.channels[member]; ok || member == channel {
+			need++
+		}
+	}
+	roomy := make([]BotInfo, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.MaxChannels == 0 || candidate.MaxChannels-len(candidate.Channels) >= need {
+			roomy = append(roomy, candidate)
+		}
+	}
+	if len(roomy) == 0 {
+		return candidates
+	}
+	return roomy
+}
+
+// colocateGroups moves grouped channels running apart onto the schedulable bot running most of their group
+func (s *service) colocateGroups() {
+	names := make([]string, 0, len(s.groups))
+	for name := range s.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		counts := make(map[uuid.UUID]int)
+		for _, member := range s.groups[name] {
+			if state, ok := s.channels[member]; ok {
+				for _, id := range state.bots {
+					counts[id]++
+				}
+			}
+		}
+		anchor, best := uuid.Nil, 0
+		for _, id := range sortedIDs(s.botInfos()) {
+			if bot := s.bots[id]; !bot.cordoned && counts[id] > best {
+				anchor, best = id, counts[id]
+			}
+		}
+		if anchor == uuid.Nil {
+			continue
+		}
+		for _, member := range s.groups[name] {
+			state, ok := s.channels[member]
+			if !ok || state.hasBot(anchor) || len(state.bots) == 0 {
+				continue
+			}
+			if _, migrating := s.migrations[member]; migrating {
+				continue
+			}
+			if err := s.moveChannel(Move{
+				Channel: member,
+				From:    state.bots[0],
+				To:      anchor,
+			}); err != nil {
+				s.logger.Warn("failed to move channel to its group", zap.String("channel", member), zap.String("group", name), zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package bots
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// WithLivenessTimeout sets how long a bot which sends heartbeats can go without one before it's evicted, 0 never
+// evicts bots
+func WithLivenessTimeout(timeout time.Duration) Option {
+	return func(s *service) {
+		s.livenessTimeout = timeout
+	}
+}
+
+// WithHeartbeats is for bots which send heartbeats, they're evicted once they go quiet for longer than the liveness
+// timeout
+func WithHeartbeats() BotOption {
+	return func(b *botState) {
+		b.heartbeats = true
+	}
+}
+
+// Heartbeat records that a bot is still alive
+// Returns ErrBotNotExist if the bot doesn't exist
+func (s *service) Heartbeat(id uuid.UUID) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	bot, ok := s.bots[id]
+	if !ok {
+		return ErrBotNotExist
+	}
+	bot.lastHeartbeat = time.Now()
+	return nil
+}
+
+// watchLiveness evicts a bot once it has gone longer than the liveness timeout without a heartbeat, joining counts as
+// the first heartbeat
+func (s *service) watchLiveness(bot *botState) {
+	bot.lastHeartbeat = time.Now()
+	if !bot.heartbeats || s.livenessTimeout <= 0 {
+		return
+	}
+	var check func()
+	check = func() {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		s.chanMux.Lock()
+		defer s.chanMux.Unlock()
+		if s.bots[bot.id] != bot {
+			// Already left
+			return
+		}
+		silent := time.Since(bot.lastHeartbeat)
+		if silent < s.livenessTimeout {
+			bot.livenessTimer = time.AfterFunc(s.livenessTimeout-silent, check)
+			return
+		}
+		bot.logger.Warn("bot stopped sending heartbeats, evicting", zap.Duration("silent", silent))
+		// Its channels move straight away rather than waiting for it to resume
+		bot.removed = true
+		bot.cancelFunc()
+		if err := s.leave(bot.id); err != nil {
+			bot.logger.Warn("failed to evict bot", zap.Error(err))
+		}
+	}
+	bot.livenessTimer = time.AfterFunc(s.livenessTimeout, check)
+}
@@ -0,0 +1,229 @@
+package bots
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrMigrating is returned when a channel is already being moved between bots
+	ErrMigrating = errors.New("channel is already migrating")
+	// ErrBotInChannel is returned when a bot is asked to join a channel it is already in
+	ErrBotInChannel = errors.New("bot is already in channel")
+	// ErrCordoned is returned when a channel is moved to a bot which is cordoned
+	ErrCordoned = errors.New("bot is cordoned")
+)
+
+type (
+	// migration is a channel being moved between bots, the source bot stays in the channel until the target confirms
+	// it has joined
+	migration struct {
+		channel   string
+		from      uuid.UUID
+		to        uuid.UUID
+		startedAt time.Time
+		timer     *time.Timer
+	}
+
+	// MigrationInfo is a struct containing information about a channel being moved between bots
+	MigrationInfo struct {
+		Channel   string    `json:"channel"`
+		From      uuid.UUID `json:"from"`
+		To        uuid.UUID `json:"to"`
+		StartedAt time.Time `json:"started_at"`
+	}
+)
+
+// WithMigrationTimeout sets how long a bot has to confirm it has joined a channel being moved to it before the move
+// is rolled back
+func WithMigrationTimeout(timeout time.Duration) Option {
+	return func(s *service) {
+		s.migrationTimeout = timeout
+	}
+}
+
+// MoveChannel moves a channel from one bot to another, the source bot leaves once the target confirms it has joined
+// Returns ErrNotInChannel if the source bot isn't in the channel
+func (s *service) MoveChannel(channel string, from, to uuid.UUID) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	return s.moveChannel(Move{
+		Channel: channel,
+		From:    from,
+		To:      to,
+	})
+}
+
+// moveChannel joins the target bot to a channel and waits for it to confirm before the source bot leaves
+func (s *service) moveChannel(m Move) error {
+	state, ok := s.channels[m.Channel]
+	if !ok || !state.hasBot(m.From) {
+		return ErrNotInChannel
+	}
+	if state.hasBot(m.To) {
+		return ErrBotInChannel
+	}
+	if state.pinnedTo(m.From) {
+		return ErrPinned
+	}
+	if _, ok := s.migrations[m.Channel]; ok {
+		return ErrMigrating
+	}
+	if _, ok := s.bots[m.From]; !ok {
+		return ErrBotNotExist
+	}
+	to, ok := s.bots[m.To]
+	if !ok {
+		return ErrBotNotExist
+	}
+	if info := to.BotInfo(); !info.hasCapacity() {
+		return ErrAtCapacity
+	} else if info.Cordoned {
+		return ErrCordoned
+	} else if !s.allowsMove(m.Channel, m.From, info) {
+		return ErrRulesNotMet
+	}
+	s.joinChannel(to, m.Channel)
+	// Both bots are in the channel until the move completes
+	state.bots = append(state.bots, to.id)
+	mig := &migration{
+		channel:   m.Channel,
+		from:      m.From,
+		to:        m.To,
+		startedAt: time.Now(),
+	}
+	mig.timer = time.AfterFunc(s.migrationTimeout, func() {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		s.chanMux.Lock()
+		defer s.chanMux.Unlock()
+		s.rollbackMigration(mig)
+	})
+	s.migrations[m.Channel] = mig
+	s.logger.Info("migrating channel",
+		zap.String("channel", m.Channel),
+		zap.String("from", m.From.String()),
+		zap.String("to", m.To.String()),
+	)
+	return nil
+}
+
+// ConfirmJoin is called by a bot once it has joined a channel, for bots which acknowledge JOINs by channel rather than
+// with AckCommand
+// Returns ErrBotNotExist if the bot doesn't exist
+func (s *service) ConfirmJoin(id uuid.UUID, channel string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if _, ok := s.bots[id]; !ok {
+		return ErrBotNotExist
+	}
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	for cmdID, cmd := range s.commands {
+		if cmd.bot == id && cmd.join && cmd.channel == channel {
+			cmd.stop()
+			delete(s.commands, cmdID)
+		}
+	}
+	return s.joined(id, channel)
+}
+
+// joined records that a bot has joined a channel, completing any migration of the channel to the bot
+func (s *service) joined(id uuid.UUID, channel string) error {
+	if state, ok := s.channels[channel]; ok {
+		state.joinSucceeded()
+	}
+	mig, ok := s.migrations[channel]
+	if !ok || mig.to != id {
+		return nil
+	}
+	mig.timer.Stop()
+	delete(s.migrations, channel)
+	if state, ok := s.channels[channel]; ok {
+		state.removeBot(mig.from)
+	}
+	from, ok := s.bots[mig.from]
+	if !ok {
+		return nil
+	}
+	s.logger.Info("migration complete", zap.String("channel", channel))
+	err := s.leaveChannel(from, channel)
+	// The source bot may have room for queued channels now
+	s.distributeChannels()
+	s.drainNext(mig.from)
+	s.rollout()
+	return err
+}
+
+// rollbackMigration removes the target bot from a channel after it failed to confirm in time, or couldn't join it
+func (s *service) rollbackMigration(mig *migration) {
+	if s.migrations[mig.channel] != mig {
+		// Already completed or cancelled
+		return
+	}
+	mig.timer.Stop()
+	delete(s.migrations, mig.channel)
+	s.logger.Warn("rolling back migration",
+		zap.String("channel", mig.channel),
+		zap.String("to", mig.to.String()),
+	)
+	if state, ok := s.channels[mig.channel]; ok {
+		state.removeBot(mig.to)
+	}
+	if to, ok := s.bots[mig.to]; ok {
+		if err := s.leaveChannel(to, mig.channel); err != nil {
+			s.logger.Warn("failed to leave channel", zap.String("channel", mig.channel), zap.Error(err))
+		}
+	}
+	s.distributeChannels()
+	s.drainNext(mig.from)
+}
+
+// cancelMigration stops tracking a migration of a channel, leaving both bots in the channel as they are
+func (s *service) cancelMigration(channel string) {
+	if mig, ok := s.migrations[channel]; ok {
+		mig.timer.Stop()
+		delete(s.migrations, channel)
+	}
+}
+
+// rollbackMigrationsTo rolls back any migrations onto a bot which has disconnected, leaving the channels on the bots
+// they were moving from
+// Migrations off the bot carry on, if it resumes it's sent a LEAVE for any channel which has finished moving
+func (s *service) rollbackMigrationsTo(id uuid.UUID) {
+	for _, mig := range s.migrations {
+		if mig.to == id {
+			s.rollbackMigration(mig)
+		}
+	}
+}
+
+// cancelBotMigrations stops tracking any migrations to or from a bot
+func (s *service) cancelBotMigrations(id uuid.UUID) {
+	for channel, mig := range s.migrations {
+		if mig.from == id || mig.to == id {
+			s.cancelMigration(channel)
+		}
+	}
+}
+
+// Migrations returns the channels currently being moved between bots
+func (s *service) Migrations() []MigrationInfo {
+	s.chanMux.RLock()
+	defer s.chanMux.RUnlock()
+	migrations := make([]MigrationInfo, 0, len(s.migrations))
+	for _, mig := range s.migrations {
+		migrations = append(migrations, MigrationInfo{
+			Channel:   mig.channel,
+			From:      mig.from,
+			To:        mig.to,
+			StartedAt: mig.startedAt,
+		})
+	}
+	return migrations
+}
@@ -19,6 +19,20 @@ type Service struct {
 	mock.Mock
 }
 
+// AckCommand provides a mock function with given fields: id, commandID, ackErr
+func (_m *Service) AckCommand(id uuid.UUID, commandID string, ackErr error) error {
+	ret := _m.Called(id, commandID, ackErr)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, string, error) error); ok {
+		r0 = rf(id, commandID, ackErr)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // BotInfo provides a mock function with given fields:
 func (_m *Service) BotInfo() []bots.BotInfo {
 	ret := _m.Called()
@@ -35,6 +49,34 @@ func (_m *Service) BotInfo() []bots.BotInfo {
 	return r0
 }
 
+// CanResume provides a mock function with given fields: id, token
+func (_m *Service) CanResume(id uuid.UUID, token string) error {
+	ret := _m.Called(id, token)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, string) error); ok {
+		r0 = rf(id, token)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Canary provides a mock function with given fields:
+func (_m *Service) Canary() bots.CanaryInfo {
+	ret := _m.Called()
+
+	var r0 bots.CanaryInfo
+	if rf, ok := ret.Get(0).(func() bots.CanaryInfo); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bots.CanaryInfo)
+	}
+
+	return r0
+}
+
 // ChannelInfo provides a mock function with given fields:
 func (_m *Service) ChannelInfo() map[string][]uuid.UUID {
 	ret := _m.Called()
@@ -51,6 +93,48 @@ func (_m *Service) ChannelInfo() map[string][]uuid.UUID {
 	return r0
 }
 
+// ClearExclusions provides a mock function with given fields: filter
+func (_m *Service) ClearExclusions(filter bots.Exclusion) int {
+	ret := _m.Called(filter)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(bots.Exclusion) int); ok {
+		r0 = rf(filter)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// ConfirmJoin provides a mock function with given fields: id, channel
+func (_m *Service) ConfirmJoin(id uuid.UUID, channel string) error {
+	ret := _m.Called(id, channel)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, string) error); ok {
+		r0 = rf(id, channel)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Cordon provides a mock function with given fields: id
+func (_m *Service) Cordon(id uuid.UUID) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // DanglingChannels provides a mock function with given fields:
 func (_m *Service) DanglingChannels() []string {
 	ret := _m.Called()
@@ -67,13 +151,145 @@ func (_m *Service) DanglingChannels() []string {
 	return r0
 }
 
-// Join provides a mock function with given fields: ctx, id, botClient
-func (_m *Service) Join(ctx context.Context, id uuid.UUID, botClient proto.BotClient) context.Context {
-	ret := _m.Called(ctx, id, botClient)
+// DeleteGroup provides a mock function with given fields: name
+func (_m *Service) DeleteGroup(name string) error {
+	ret := _m.Called(name)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(name)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Drain provides a mock function with given fields: id
+func (_m *Service) Drain(id uuid.UUID) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DrainStatus provides a mock function with given fields: id
+func (_m *Service) DrainStatus(id uuid.UUID) (bots.DrainStatus, error) {
+	ret := _m.Called(id)
+
+	var r0 bots.DrainStatus
+	if rf, ok := ret.Get(0).(func(uuid.UUID) bots.DrainStatus); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Get(0).(bots.DrainStatus)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uuid.UUID) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Exclude provides a mock function with given fields: exclusion
+func (_m *Service) Exclude(exclusion bots.Exclusion) error {
+	ret := _m.Called(exclusion)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(bots.Exclusion) error); ok {
+		r0 = rf(exclusion)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Exclusions provides a mock function with given fields:
+func (_m *Service) Exclusions() []bots.Exclusion {
+	ret := _m.Called()
+
+	var r0 []bots.Exclusion
+	if rf, ok := ret.Get(0).(func() []bots.Exclusion); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bots.Exclusion)
+		}
+	}
+
+	return r0
+}
+
+// FailedChannels provides a mock function with given fields:
+func (_m *Service) FailedChannels() []bots.FailedChannel {
+	ret := _m.Called()
+
+	var r0 []bots.FailedChannel
+	if rf, ok := ret.Get(0).(func() []bots.FailedChannel); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bots.FailedChannel)
+		}
+	}
+
+	return r0
+}
+
+// Groups provides a mock function with given fields:
+func (_m *Service) Groups() map[string][]string {
+	ret := _m.Called()
+
+	var r0 map[string][]string
+	if rf, ok := ret.Get(0).(func() map[string][]string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(map[string][]string)
+		}
+	}
+
+	return r0
+}
+
+// Heartbeat provides a mock function with given fields: id
+func (_m *Service) Heartbeat(id uuid.UUID) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Join provides a mock function with given fields: ctx, id, botClient, opts
+func (_m *Service) Join(ctx context.Context, id uuid.UUID, botClient proto.BotClient, opts ...bots.BotOption) context.Context {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, id, botClient)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
 
 	var r0 context.Context
-	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, proto.BotClient) context.Context); ok {
-		r0 = rf(ctx, id, botClient)
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, proto.BotClient, ...bots.BotOption) context.Context); ok {
+		r0 = rf(ctx, id, botClient, opts...)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(context.Context)
@@ -83,13 +299,20 @@ func (_m *Service) Join(ctx context.Context, id uuid.UUID, botClient proto.BotCl
 	return r0
 }
 
-// JoinChannel provides a mock function with given fields: channel
-func (_m *Service) JoinChannel(channel string) error {
-	ret := _m.Called(channel)
+// JoinChannel provides a mock function with given fields: channel, opts
+func (_m *Service) JoinChannel(channel string, opts ...bots.ChannelOption) error {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, channel)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(channel)
+	if rf, ok := ret.Get(0).(func(string, ...bots.ChannelOption) error); ok {
+		r0 = rf(channel, opts...)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -125,6 +348,110 @@ func (_m *Service) LeaveChannel(channel string) error {
 	return r0
 }
 
+// Migrations provides a mock function with given fields:
+func (_m *Service) Migrations() []bots.MigrationInfo {
+	ret := _m.Called()
+
+	var r0 []bots.MigrationInfo
+	if rf, ok := ret.Get(0).(func() []bots.MigrationInfo); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bots.MigrationInfo)
+		}
+	}
+
+	return r0
+}
+
+// MoveChannel provides a mock function with given fields: channel, from, to
+func (_m *Service) MoveChannel(channel string, from uuid.UUID, to uuid.UUID) error {
+	ret := _m.Called(channel, from, to)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, uuid.UUID, uuid.UUID) error); ok {
+		r0 = rf(channel, from, to)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PendingChannels provides a mock function with given fields:
+func (_m *Service) PendingChannels() []bots.PendingChannel {
+	ret := _m.Called()
+
+	var r0 []bots.PendingChannel
+	if rf, ok := ret.Get(0).(func() []bots.PendingChannel); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bots.PendingChannel)
+		}
+	}
+
+	return r0
+}
+
+// PlanRebalance provides a mock function with given fields:
+func (_m *Service) PlanRebalance() bots.RebalancePlan {
+	ret := _m.Called()
+
+	var r0 bots.RebalancePlan
+	if rf, ok := ret.Get(0).(func() bots.RebalancePlan); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bots.RebalancePlan)
+	}
+
+	return r0
+}
+
+// PromoteCanary provides a mock function with given fields:
+func (_m *Service) PromoteCanary() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Rebalance provides a mock function with given fields:
+func (_m *Service) Rebalance() bots.RebalancePlan {
+	ret := _m.Called()
+
+	var r0 bots.RebalancePlan
+	if rf, ok := ret.Get(0).(func() bots.RebalancePlan); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bots.RebalancePlan)
+	}
+
+	return r0
+}
+
+// RemovableBots provides a mock function with given fields:
+func (_m *Service) RemovableBots() []bots.BotInfo {
+	ret := _m.Called()
+
+	var r0 []bots.BotInfo
+	if rf, ok := ret.Get(0).(func() []bots.BotInfo); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bots.BotInfo)
+		}
+	}
+
+	return r0
+}
+
 // RemoveBot provides a mock function with given fields: id
 func (_m *Service) RemoveBot(id uuid.UUID) error {
 	ret := _m.Called(id)
@@ -138,3 +465,119 @@ func (_m *Service) RemoveBot(id uuid.UUID) error {
 
 	return r0
 }
+
+// ReportLoad provides a mock function with given fields: id, rates
+func (_m *Service) ReportLoad(id uuid.UUID, rates map[string]float64) error {
+	ret := _m.Called(id, rates)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID, map[string]float64) error); ok {
+		r0 = rf(id, rates)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Resume provides a mock function with given fields: ctx, id, token, botClient, opts
+func (_m *Service) Resume(ctx context.Context, id uuid.UUID, token string, botClient proto.BotClient, opts ...bots.BotOption) (context.Context, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, ctx, id, token, botClient)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 context.Context
+	if rf, ok := ret.Get(0).(func(context.Context, uuid.UUID, string, proto.BotClient, ...bots.BotOption) context.Context); ok {
+		r0 = rf(ctx, id, token, botClient, opts...)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(context.Context)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, uuid.UUID, string, proto.BotClient, ...bots.BotOption) error); ok {
+		r1 = rf(ctx, id, token, botClient, opts...)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RollbackCanary provides a mock function with given fields:
+func (_m *Service) RollbackCanary() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetCanary provides a mock function with given fields: version, percent, channels
+func (_m *Service) SetCanary(version string, percent int, channels []string) error {
+	ret := _m.Called(version, percent, channels)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, int, []string) error); ok {
+		r0 = rf(version, percent, channels)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SetGroup provides a mock function with given fields: name, channels
+func (_m *Service) SetGroup(name string, channels []string) error {
+	ret := _m.Called(name, channels)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []string) error); ok {
+		r0 = rf(name, channels)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Uncordon provides a mock function with given fields: id
+func (_m *Service) Uncordon(id uuid.UUID) error {
+	ret := _m.Called(id)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(uuid.UUID) error); ok {
+		r0 = rf(id)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UnschedulableChannels provides a mock function with given fields:
+func (_m *Service) UnschedulableChannels() []bots.UnschedulableChannel {
+	ret := _m.Called()
+
+	var r0 []bots.UnschedulableChannel
+	if rf, ok := ret.Get(0).(func() []bots.UnschedulableChannel); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]bots.UnschedulableChannel)
+		}
+	}
+
+	return r0
+}
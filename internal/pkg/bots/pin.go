@@ -0,0 +1,116 @@
+package bots
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrPinned is returned when a channel is moved off the bot it is pinned to
+var ErrPinned = errors.New("channel is pinned to bot")
+
+// WithPinnedBot pins a channel to a bot, the bot always runs one of the channel's replicas and the channel is never
+// moved off it
+// Channels can only be pinned to a bot which isn't connected or resuming if there's a pin fallback
+func WithPinnedBot(id uuid.UUID) ChannelOption {
+	return func(c *channelState) {
+		c.pinned = id
+	}
+}
+
+// WithPinFallback sets how long a pinned channel waits for its bot before being placed on another bot, by default
+// pinned channels wait forever
+func WithPinFallback(fallback time.Duration) Option {
+	return func(s *service) {
+		s.pinFallback = fallback
+	}
+}
+
+// knownBot returns whether a bot is connected or waiting to resume its session
+func (s *service) knownBot(id uuid.UUID) bool {
+	if _, ok := s.bots[id]; ok {
+		return true
+	}
+	_, ok := s.sessions[id]
+	return ok
+}
+
+// isPinned returns whether the channel is pinned to a bot
+func (c *channelState) isPinned() bool {
+	return c.pinned != uuid.Nil
+}
+
+// pinnedTo returns whether the channel is pinned to the given bot
+func (c *channelState) pinnedTo(id uuid.UUID) bool {
+	return c.isPinned() && c.pinned == id
+}
+
+// placePinned returns the bot a channel is pinned to if it should be placed there, or nil to use the placement
+// strategy
+// Returns ErrNoCandidates if the last replica is being held for the pinned bot to come back
+func (s *service) placePinned(channel string, state *channelState) (*botState, error) {
+	if !state.isPinned() || state.hasBot(state.pinned) {
+		return nil, nil
+	}
+	if bot, ok := s.bots[state.pinned]; ok && bot.BotInfo().isSchedulable() && !s.excluded(channel, bot.BotInfo()) {
+		state.pinLostAt = time.Time{}
+		return bot, nil
+	}
+	s.waitForPinnedBot(channel, state)
+	if state.missingReplicas() == 1 && !s.pinFallbackExpired(state) {
+		return nil, fmt.Errorf("waiting for pinned bot %s: %w", state.pinned, ErrNoCandidates)
+	}
+	return nil, nil
+}
+
+// pinFallbackExpired returns whether a pinned channel has waited long enough for its bot to be placed elsewhere
+func (s *service) pinFallbackExpired(state *channelState) bool {
+	return s.pinFallback > 0 && !state.pinLostAt.IsZero() && time.Since(state.pinLostAt) >= s.pinFallback
+}
+
+// waitForPinnedBot starts the fallback timer for a channel whose pinned bot isn't available
+func (s *service) waitForPinnedBot(channel string, state *channelState) {
+	if !state.isPinned() || !state.pinLostAt.IsZero() {
+		return
+	}
+	s.logger.Info("waiting for pinned bot", zap.String("channel", channel), zap.String("bot_id", state.pinned.String()))
+	state.pinLostAt = time.Now()
+	if s.pinFallback > 0 {
+		time.AfterFunc(s.pinFallback, func() {
+			s.mux.Lock()
+			defer s.mux.Unlock()
+			s.chanMux.Lock()
+			defer s.chanMux.Unlock()
+			s.distributeChannels()
+		})
+	}
+}
+
+// restorePinnedChannels moves channels back onto the bot they're pinned to after they fell back to other bots
+func (s *service) restorePinnedChannels(id uuid.UUID) {
+	for _, channel := range sortedChannels(s.channels) {
+		state := s.channels[channel]
+		if !state.pinnedTo(id) {
+			continue
+		}
+		if state.hasBot(id) {
+			state.pinLostAt = time.Time{}
+			continue
+		}
+		if len(state.bots) == 0 {
+			continue
+		}
+		if err := s.moveChannel(Move{
+			Channel: channel,
+			From:    state.bots[0],
+			To:      id,
+		}); err != nil {
+			s.logger.Warn("failed to move channel back to pinned bot", zap.String("channel", channel), zap.Error(err))
+			continue
+		}
+		state.pinLostAt = time.Time{}
+	}
+}
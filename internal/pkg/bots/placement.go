@@ -0,0 +1,299 @@
+package bots
+
+import (
+	"errors"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoCandidates is returned when a placement strategy has no bots it can assign a channel to
+var ErrNoCandidates = errors.New("no candidate bots")
+
+type (
+	// PlacementStrategy decides which bot a channel should be assigned to
+	PlacementStrategy interface {
+		// Place picks one of the candidates to run the channel
+		// Returns ErrNoCandidates if none of the candidates can take the channel
+		Place(channel string, candidates []BotInfo) (uuid.UUID, error)
+	}
+
+	// PreferredPlacement is implemented by placement strategies which have a preferred bot for each channel, rebalances
+	// move channels back onto their preferred bot rather than evening out the number of channels on each bot
+	PreferredPlacement interface {
+		PlacementStrategy
+		// PreferredBot picks the candidate which should be running the channel, the bot currently running it is one of
+		// the candidates
+		// Returns ErrNoCandidates if none of the candidates can take the channel
+		PreferredBot(channel string, candidates []BotInfo) (uuid.UUID, error)
+	}
+
+	// WeightFunc returns the relative weight of a bot, a bot with double the weight will be given double the channels
+	WeightFunc func(bot BotInfo) float64
+
+	leastChannels struct{}
+
+	leastLoaded struct{}
+
+	roundRobin struct {
+		mux  sync.Mutex
+		next int
+	}
+
+	random struct {
+		mux  sync.Mutex
+		rand *rand.Rand
+	}
+
+	weighted struct {
+		weight WeightFunc
+	}
+
+	binPack struct{}
+
+	consistentHash struct {
+		replicas   int
+		loadFactor float64
+		mux        sync.Mutex
+		// ring is cached for the set of bots in ringKey, as the candidates rarely change between placements
+		ring    []ringPoint
+		ringKey string
+	}
+
+	// ringPoint is one of a bot's positions on the hash ring
+	ringPoint struct {
+		hash uint64
+		bot  uuid.UUID
+	}
+)
+
+// LeastChannels places channels on the bot currently running the fewest channels, ties go to the lowest bot ID
+func LeastChannels() PlacementStrategy {
+	return leastChannels{}
+}
+
+// LeastLoaded places channels on the bot with the lowest reported load, falling back to the fewest channels and then
+// the lowest bot ID when loads are equal
+func LeastLoaded() PlacementStrategy {
+	return leastLoaded{}
+}
+
+// RoundRobin places channels on each bot in turn, ordered by bot ID
+func RoundRobin() PlacementStrategy {
+	return &roundRobin{}
+}
+
+// Random places channels on a random bot, seeded with seed
+func Random(seed int64) PlacementStrategy {
+	return &random{
+		rand: rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Weighted places channels on the bot with the fewest channels relative to its weight
+// Bots with a weight of zero or less are never picked, ties go to the lowest bot ID
+func Weighted(weight WeightFunc) PlacementStrategy {
+	return weighted{
+		weight: weight,
+	}
+}
+
+// BinPack places channels on the bot with the most channels which still has capacity, packing channels onto as few
+// bots as possible so the rest are left empty and can be removed
+// Bots without a channel limit take every channel, and as rebalancing spreads channels back out it's usually paired
+// with WithAutoRebalance(false)
+func BinPack() PlacementStrategy {
+	return binPack{}
+}
+
+// ConsistentHash places each channel on the first bot clockwise from the channel's position on a hash ring, so the
+// same channel maps onto the same bot and adding or removing a bot only changes where roughly 1/N of channels go
+// Each bot is given virtualNodes positions on the ring to even out the spread, and loads are bounded by skipping any
+// bot with more than loadFactor times the average number of channels, loadFactor is at least 1
+// Rebalances move channels back to the bot the ring places them on rather than evening out the number of channels
+func ConsistentHash(virtualNodes int, loadFactor float64) PlacementStrategy {
+	if virtualNodes < 1 {
+		virtualNodes = 1
+	}
+	if loadFactor < 1 {
+		loadFactor = 1
+	}
+	return &consistentHash{
+		replicas:   virtualNodes,
+		loadFactor: loadFactor,
+	}
+}
+
+func (leastChannels) Place(_ string, candidates []BotInfo) (uuid.UUID, error) {
+	if len(candidates) == 0 {
+		return uuid.Nil, ErrNoCandidates
+	}
+	sorted := make([]BotInfo, len(candidates))
+	copy(sorted, candidates)
+	sort.Sort(channelSort(sorted))
+	return sorted[0].ID, nil
+}
+
+func (leastLoaded) Place(_ string, candidates []BotInfo) (uuid.UUID, error) {
+	if len(candidates) == 0 {
+		return uuid.Nil, ErrNoCandidates
+	}
+	sorted := make([]BotInfo, len(candidates))
+	copy(sorted, candidates)
+	sort.Sort(loadSort(sorted))
+	return sorted[0].ID, nil
+}
+
+func (r *roundRobin) Place(_ string, candidates []BotInfo) (uuid.UUID, error) {
+	if len(candidates) == 0 {
+		return uuid.Nil, ErrNoCandidates
+	}
+	ids := sortedIDs(candidates)
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	id := ids[r.next%len(ids)]
+	r.next++
+	return id, nil
+}
+
+func (r *random) Place(_ string, candidates []BotInfo) (uuid.UUID, error) {
+	if len(candidates) == 0 {
+		return uuid.Nil, ErrNoCandidates
+	}
+	// Sort first so the same seed gives the same result regardless of the order bots are given in
+	ids := sortedIDs(candidates)
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return ids[r.rand.Intn(len(ids))], nil
+}
+
+func (w weighted) Place(_ string, candidates []BotInfo) (uuid.UUID, error) {
+	var (
+		best      uuid.UUID
+		bestScore float64
+		found     bool
+	)
+	for _, bot := range candidates {
+		weight := w.weight(bot)
+		if weight <= 0 {
+			continue
+		}
+		score := float64(len(bot.Channels)) / weight
+		if !found || score < bestScore || (score == bestScore && bot.ID.String() < best.String()) {
+			best, bestScore, found = bot.ID, score, true
+		}
+	}
+	if !found {
+		return uuid.Nil, ErrNoCandidates
+	}
+	return best, nil
+}
+
+func (binPack) Place(_ string, candidates []BotInfo) (uuid.UUID, error) {
+	sorted := make([]BotInfo, len(candidates))
+	copy(sorted, candidates)
+	sort.Slice(sorted, func(i, j int) bool {
+		left, right := len(sorted[i].Channels), len(sorted[j].Channels)
+		if left == right {
+			return sorted[i].ID.String() < sorted[j].ID.String()
+		}
+		return left > right
+	})
+	for _, bot := range sorted {
+		if bot.hasCapacity() {
+			return bot.ID, nil
+		}
+	}
+	return uuid.Nil, ErrNoCandidates
+}
+
+func (c *consistentHash) Place(channel string, candidates []BotInfo) (uuid.UUID, error) {
+	if len(candidates) == 0 {
+		return uuid.Nil, ErrNoCandidates
+	}
+	byID := make(map[uuid.UUID]BotInfo, len(candidates))
+	total := 0
+	for _, bot := range candidates {
+		byID[bot.ID] = bot
+		total += len(bot.Channels)
+	}
+	ring := c.ringFor(candidates)
+	// Including the channel being placed, at least one bot is always under the bound
+	bound := math.Ceil(c.loadFactor * float64(total+1) / float64(len(candidates)))
+	hash := hashKey(channel)
+	start := sort.Search(len(ring), func(i int) bool {
+		return ring[i].hash >= hash
+	})
+	for i := 0; i < len(ring); i++ {
+		bot := byID[ring[(start+i)%len(ring)].bot]
+		if float64(len(bot.Channels)) < bound {
+			return bot.ID, nil
+		}
+	}
+	return uuid.Nil, ErrNoCandidates
+}
+
+// PreferredBot is wherever the ring places the channel, so channels move back onto the ring when bots join or leave
+func (c *consistentHash) PreferredBot(channel string, candidates []BotInfo) (uuid.UUID, error) {
+	return c.Place(channel, candidates)
+}
+
+// ringFor returns the hash ring containing the candidates, rebuilding it if the candidates have changed
+func (c *consistentHash) ringFor(candidates []BotInfo) []ringPoint {
+	ids := sortedIDs(candidates)
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = id.String()
+	}
+	key := strings.Join(keys, ",")
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.ringKey == key {
+		return c.ring
+	}
+	ring := make([]ringPoint, 0, len(ids)*c.replicas)
+	for _, id := range ids {
+		for v := 0; v < c.replicas; v++ {
+			ring = append(ring, ringPoint{
+				hash: hashKey(id.String() + "-" + strconv.Itoa(v)),
+				bot:  id,
+			})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i].hash < ring[j].hash
+	})
+	c.ring, c.ringKey = ring, key
+	return ring
+}
+
+// hashKey hashes a key onto the ring, FNV on its own clusters similar keys so the result is mixed to spread them out
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// sortedIDs returns the IDs of the bots sorted so placement doesn't depend on map iteration order
+func sortedIDs(bots []BotInfo) []uuid.UUID {
+	ids := make([]uuid.UUID, len(bots))
+	for i, bot := range bots {
+		ids[i] = bot.ID
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() < ids[j].String()
+	})
+	return ids
+}
@@ -0,0 +1,260 @@
+package bots_test
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
+	"github.com/ch629/bot-orchestrator/internal/pkg/proto/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+var (
+	botOne = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	botTwo = uuid.MustParse("00000000-0000-0000-0000-000000000002")
+)
+
+func Test_LeastChannels(t *testing.T) {
+	strategy := bots.LeastChannels()
+	id, err := strategy.Place("foo", []bots.BotInfo{
+		{ID: botOne, Channels: []string{"bar", "baz"}},
+		{ID: botTwo, Channels: []string{"qux"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, botTwo, id)
+
+	// Ties go to the lowest ID whichever order the bots are given in
+	id, err = strategy.Place("foo", []bots.BotInfo{{ID: botTwo}, {ID: botOne}})
+	require.NoError(t, err)
+	require.Equal(t, botOne, id)
+
+	_, err = strategy.Place("foo", nil)
+	require.ErrorIs(t, err, bots.ErrNoCandidates)
+}
+
+func Test_RoundRobin(t *testing.T) {
+	strategy := bots.RoundRobin()
+	// Order given shouldn't matter, bots are taken in ID order
+	candidates := []bots.BotInfo{{ID: botTwo}, {ID: botOne}}
+	var placed []uuid.UUID
+	for i := 0; i < 4; i++ {
+		id, err := strategy.Place("foo", candidates)
+		require.NoError(t, err)
+		placed = append(placed, id)
+	}
+	require.Equal(t, []uuid.UUID{botOne, botTwo, botOne, botTwo}, placed)
+
+	_, err := strategy.Place("foo", nil)
+	require.ErrorIs(t, err, bots.ErrNoCandidates)
+}
+
+func Test_Random(t *testing.T) {
+	candidates := []bots.BotInfo{{ID: botOne}, {ID: botTwo}}
+	reversed := []bots.BotInfo{{ID: botTwo}, {ID: botOne}}
+	first, second := bots.Random(42), bots.Random(42)
+	for i := 0; i < 10; i++ {
+		a, err := first.Place("foo", candidates)
+		require.NoError(t, err)
+		b, err := second.Place("foo", reversed)
+		require.NoError(t, err)
+		require.Equal(t, a, b, "the same seed should give the same placements")
+	}
+
+	_, err := first.Place("foo", nil)
+	require.ErrorIs(t, err, bots.ErrNoCandidates)
+}
+
+func Test_Weighted(t *testing.T) {
+	weights := map[uuid.UUID]float64{
+		botOne: 3,
+		botTwo: 1,
+	}
+	strategy := bots.Weighted(func(bot bots.BotInfo) float64 {
+		return weights[bot.ID]
+	})
+	// botOne has more channels but triple the weight
+	id, err := strategy.Place("foo", []bots.BotInfo{
+		{ID: botOne, Channels: []string{"a", "b"}},
+		{ID: botTwo, Channels: []string{"c"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, botOne, id)
+
+	// Ties go to the lowest ID whichever order the bots are given in
+	id, err = strategy.Place("foo", []bots.BotInfo{
+		{ID: botTwo, Channels: []string{"c"}},
+		{ID: botOne, Channels: []string{"a", "b", "c"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, botOne, id)
+
+	// Bots without any weight are never picked
+	weights[botOne] = 0
+	weights[botTwo] = 0
+	_, err = strategy.Place("foo", []bots.BotInfo{{ID: botOne}, {ID: botTwo}})
+	require.ErrorIs(t, err, bots.ErrNoCandidates)
+}
+
+func Test_LeastLoaded(t *testing.T) {
+	strategy := bots.LeastLoaded()
+	// One busy channel outweighs several quiet ones
+	id, err := strategy.Place("foo", []bots.BotInfo{
+		{ID: botOne, Channels: []string{"busy"}, Load: 100},
+		{ID: botTwo, Channels: []string{"a", "b", "c"}, Load: 3},
+	})
+	require.NoError(t, err)
+	require.Equal(t, botTwo, id)
+
+	// Ties go to the lowest ID whichever order the bots are given in
+	id, err = strategy.Place("foo", []bots.BotInfo{
+		{ID: botTwo, Channels: []string{"a"}, Load: 3},
+		{ID: botOne, Channels: []string{"b"}, Load: 3},
+	})
+	require.NoError(t, err)
+	require.Equal(t, botOne, id)
+
+	_, err = strategy.Place("foo", nil)
+	require.ErrorIs(t, err, bots.ErrNoCandidates)
+}
+
+func Test_ConsistentHash(t *testing.T) {
+	strategy := bots.ConsistentHash(100, 1.25)
+	candidates := []bots.BotInfo{{ID: botOne}, {ID: botTwo}}
+	reversed := []bots.BotInfo{{ID: botTwo}, {ID: botOne}}
+	for _, channel := range []string{"foo", "bar", "baz"} {
+		a, err := strategy.Place(channel, candidates)
+		require.NoError(t, err)
+		b, err := strategy.Place(channel, reversed)
+		require.NoError(t, err)
+		require.Equal(t, a, b, "a channel should always map onto the same bot")
+	}
+
+	// A bot over the load bound is skipped
+	id, err := strategy.Place("foo", []bots.BotInfo{{ID: botOne, Channels: []string{"a", "b", "c"}}, {ID: botTwo}})
+	require.NoError(t, err)
+	require.Equal(t, botTwo, id)
+
+	_, err = strategy.Place("foo", nil)
+	require.ErrorIs(t, err, bots.ErrNoCandidates)
+}
+
+// placements returns which bot each channel is on, channels being migrated are on the bot they're moving from
+func placements(service bots.Service) map[string]uuid.UUID {
+	placed := make(map[string]uuid.UUID)
+	for channel, ids := range service.ChannelInfo() {
+		if len(ids) > 0 {
+			placed[channel] = ids[0]
+		}
+	}
+	return placed
+}
+
+// churn returns the fraction of channels placed on a different bot
+func churn(before, after map[string]uuid.UUID) float64 {
+	moved := 0
+	for channel, id := range before {
+		if after[channel] != id {
+			moved++
+		}
+	}
+	return float64(moved) / float64(len(before))
+}
+
+func Test_ConsistentHashChurn(t *testing.T) {
+	const botCount = 10
+	ids := make([]uuid.UUID, botCount+1)
+	for i := range ids {
+		ids[i] = uuid.MustParse(fmt.Sprintf("00000000-0000-0000-0000-%012d", i+1))
+	}
+	channels := make([]string, 300)
+	for i := range channels {
+		channels[i] = fmt.Sprintf("channel-%d", i)
+	}
+
+	tests := []struct {
+		name     string
+		strategy bots.PlacementStrategy
+		// maxChurn is the most channels allowed to move when a bot joins or leaves
+		maxChurn float64
+	}{
+		{
+			name:     "Unbounded",
+			strategy: bots.ConsistentHash(200, math.MaxFloat64),
+			// Ideally 1/11 of channels move onto the new bot
+			maxChurn: 0.15,
+		},
+		{
+			name:     "Bounded load",
+			strategy: bots.ConsistentHash(200, 1.25),
+			maxChurn: 0.2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := bots.New(zap.NewNop(),
+				bots.WithPlacementStrategy(tt.strategy),
+				bots.WithRebalanceBatch(len(channels), time.Hour),
+				bots.WithMigrationTimeout(time.Hour),
+			)
+			mockBotClient := &mocks.BotClient{}
+			mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+			mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
+			for _, id := range ids[:botCount] {
+				_ = service.Join(context.Background(), id, mockBotClient)
+			}
+			for _, channel := range channels {
+				require.NoError(t, service.JoinChannel(channel))
+			}
+			before := placements(service)
+
+			// The rebalance onto a new bot only moves the channels which hash to it
+			_ = service.Join(context.Background(), ids[botCount], mockBotClient)
+			migrations := service.Migrations()
+			added := float64(len(migrations)) / float64(len(channels))
+			for _, migration := range migrations {
+				require.NoError(t, service.ConfirmJoin(migration.To, migration.Channel))
+			}
+			require.Empty(t, service.Migrations())
+			joined := placements(service)
+			require.Equal(t, added, churn(before, joined))
+
+			// Only the channels on a bot which leaves are moved
+			require.NoError(t, service.Leave(ids[0]))
+			removed := churn(joined, placements(service))
+			t.Logf("churn adding a bot: %.3f, removing a bot: %.3f", added, removed)
+			require.Greater(t, added, 0.0)
+			require.LessOrEqual(t, added, tt.maxChurn)
+			require.LessOrEqual(t, removed, tt.maxChurn)
+			require.Empty(t, service.DanglingChannels())
+		})
+	}
+}
+
+func Test_BinPack(t *testing.T) {
+	strategy := bots.BinPack()
+	// The busiest bot with room is picked
+	id, err := strategy.Place("foo", []bots.BotInfo{
+		{ID: botOne, Channels: []string{"bar"}},
+		{ID: botTwo, Channels: []string{"baz", "qux"}, MaxChannels: 3},
+	})
+	require.NoError(t, err)
+	require.Equal(t, botTwo, id)
+
+	// Full bots are skipped
+	id, err = strategy.Place("foo", []bots.BotInfo{
+		{ID: botOne, Channels: []string{"bar"}},
+		{ID: botTwo, Channels: []string{"baz", "qux"}, MaxChannels: 2},
+	})
+	require.NoError(t, err)
+	require.Equal(t, botOne, id)
+
+	_, err = strategy.Place("foo", []bots.BotInfo{{ID: botOne, Channels: []string{"bar"}, MaxChannels: 1}})
+	require.ErrorIs(t, err, bots.ErrNoCandidates)
+}
@@ -0,0 +1,186 @@
+package bots
+
+import (
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+type (
+	// Move is a single channel being moved from one bot to another
+	Move struct {
+		Channel string    `json:"channel"`
+		From    uuid.UUID `json:"from"`
+		To      uuid.UUID `json:"to"`
+	}
+
+	// RebalancePlan is the set of moves needed to balance the bots, with the number of channels each bot has before and
+	// after the moves
+	RebalancePlan struct {
+		Moves  []Move            `json:"moves"`
+		Before map[uuid.UUID]int `json:"before"`
+		After  map[uuid.UUID]int `json:"after"`
+	}
+
+	// moveFilter returns whether a channel can be moved from one bot to another, a nil moveFilter allows every move
+	moveFilter func(channel string, from uuid.UUID, to BotInfo) bool
+)
+
+// PlanRebalance works out every move needed so that no bot has more than tolerance channels more than any other
+// schedulable bot with spare capacity, channels in fixed are never moved
+func PlanRebalance(bots []BotInfo, fixed map[string]struct{}, tolerance int) RebalancePlan {
+	// Every move brings a busy bot and the quietest bot closer together, so the plan always finishes without a limit
+	return rebalancePlan(bots, planRebalance(bots, fixed, nil, tolerance, math.MaxInt32, nil))
+}
+
+// rebalancePlan describes the number of channels each bot has before and after the moves
+func rebalancePlan(bots []BotInfo, moves []Move) RebalancePlan {
+	plan := RebalancePlan{
+		Moves:  make([]Move, 0),
+		Before: make(map[uuid.UUID]int, len(bots)),
+		After:  make(map[uuid.UUID]int, len(bots)),
+	}
+	for _, bot := range bots {
+		plan.Before[bot.ID] = len(bot.Channels)
+		plan.After[bot.ID] = len(bot.Channels)
+	}
+	for _, m := range moves {
+		plan.Moves = append(plan.Moves, m)
+		plan.After[m.From]--
+		plan.After[m.To]++
+	}
+	return plan
+}
+
+// planRebalance works out which channels to move so that no bot has more than tolerance channels more than any other
+// schedulable bot with spare capacity, channels in fixed are never moved and bots with nothing which can move are skipped
+// The channels of a group on a bot are moved together, one after the other, so the limit can be passed to finish moving
+// a group, and only moves allowed by the filter are made
+func planRebalance(bots []BotInfo, fixed map[string]struct{}, groups map[string][]string, tolerance, limit int, allowed moveFilter) []Move {
+	if len(bots) < 2 {
+		return nil
+	}
+	groupOf := make(map[string][]string)
+	for _, members := range groups {
+		for _, member := range members {
+			groupOf[member] = members
+		}
+	}
+	// Copy the channels so the plan can be applied to the snapshot as it's built
+	channels := make(map[uuid.UUID]map[string]struct{}, len(bots))
+	infos := make(map[uuid.UUID]BotInfo, len(bots))
+	ids := make([]uuid.UUID, 0, len(bots))
+	for _, bot := range bots {
+		channels[bot.ID] = make(map[string]struct{}, len(bot.Channels))
+		for _, ch := range bot.Channels {
+			channels[bot.ID][ch] = struct{}{}
+		}
+		infos[bot.ID] = bot
+		ids = append(ids, bot.ID)
+	}
+	isSchedulable := func(id uuid.UUID) bool {
+		info := infos[id]
+		return !info.Cordoned && (info.MaxChannels == 0 || len(channels[id]) < info.MaxChannels)
+	}
+	// fits returns whether moving n channels onto a bot brings it closer to the busiest without passing its capacity
+	fits := func(from, to uuid.UUID, n int) bool {
+		info := infos[to]
+		return n < len(channels[from])-len(channels[to]) && (info.MaxChannels == 0 || len(channels[to])+n <= info.MaxChannels)
+	}
+
+	var moves []Move
+	for len(moves) < limit {
+		sort.Slice(ids, func(i, j int) bool {
+			left, right := len(channels[ids[i]]), len(channels[ids[j]])
+			if left == right {
+				// Break ties the same way every time
+				return ids[i].String() < ids[j].String()
+			}
+			return left < right
+		})
+		quietest, found := uuid.Nil, false
+		for _, id := range ids {
+			if isSchedulable(id) {
+				quietest, found = id, true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+		// Start with the busiest bot, falling back to the next busiest while a bot has nothing which can move
+		var (
+			from uuid.UUID
+			unit []string
+		)
+		for i := len(ids) - 1; i >= 0 && len(unit) == 0; i-- {
+			busy := ids[i]
+			if len(channels[busy])-len(channels[quietest]) <= tolerance {
+				break
+			}
+			unit, _ = movableChannels(channels[busy], channels[quietest], fixed, groupOf, func(ch string) bool {
+				return allowed == nil || allowed(ch, busy, infos[quietest])
+			}, func(n int) bool {
+				return fits(busy, quietest, n)
+			})
+			from = busy
+		}
+		if len(unit) == 0 {
+			break
+		}
+		for _, channel := range unit {
+			delete(channels[from], channel)
+			channels[quietest][channel] = struct{}{}
+			moves = append(moves, Move{
+				Channel: channel,
+				From:    from,
+				To:      quietest,
+			})
+		}
+	}
+	return moves
+}
+
+// movableChannels picks the first channel, alphabetically, in from which isn't already in to, isn't fixed and is
+// allowed to move, along with the rest of its group in from which must move with it
+// Groups are only picked if every channel in them can move and they fit
+func movableChannels(from, to, fixed map[string]struct{}, groupOf map[string][]string, allowed func(channel string) bool, fits func(n int) bool) ([]string, bool) {
+	movable := func(ch string) bool {
+		_, inTo := to[ch]
+		_, isFixed := fixed[ch]
+		return !inTo && !isFixed && allowed(ch)
+	}
+	candidates := make([]string, 0, len(from))
+	for ch := range from {
+		candidates = append(candidates, ch)
+	}
+	sort.Strings(candidates)
+	for _, ch := range candidates {
+		members, grouped := groupOf[ch]
+		if !grouped {
+			if movable(ch) {
+				return []string{ch}, true
+			}
+			continue
+		}
+		unit := make([]string, 0, len(members))
+		for _, member := range members {
+			if _, ok := from[member]; ok {
+				unit = append(unit, member)
+			}
+		}
+		if unit[0] != ch {
+			// Already considered with the first of its group
+			continue
+		}
+		ok := fits(len(unit))
+		for _, member := range unit {
+			ok = ok && movable(member)
+		}
+		if ok {
+			return unit, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,201 @@
+package bots
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_planRebalance(t *testing.T) {
+	busy := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	idle := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	other := uuid.MustParse("00000000-0000-0000-0000-000000000003")
+
+	tests := []struct {
+		name      string
+		bots      []BotInfo
+		fixed     map[string]struct{}
+		groups    map[string][]string
+		tolerance int
+		limit     int
+		allowed   moveFilter
+		expected  []Move
+	}{
+		{
+			name: "Moves channels onto the idle bot",
+			bots: []BotInfo{
+				{ID: busy, Channels: []string{"a", "b", "c", "d"}},
+				{ID: idle},
+			},
+			tolerance: 1,
+			limit:     10,
+			expected: []Move{
+				{Channel: "a", From: busy, To: idle},
+				{Channel: "b", From: busy, To: idle},
+			},
+		},
+		{
+			name: "Within tolerance",
+			bots: []BotInfo{
+				{ID: busy, Channels: []string{"a", "b", "c"}},
+				{ID: idle, Channels: []string{"d"}},
+			},
+			tolerance: 2,
+			limit:     10,
+		},
+		{
+			name: "Limited number of moves",
+			bots: []BotInfo{
+				{ID: busy, Channels: []string{"a", "b", "c", "d"}},
+				{ID: idle},
+			},
+			tolerance: 1,
+			limit:     1,
+			expected: []Move{
+				{Channel: "a", From: busy, To: idle},
+			},
+		},
+		{
+			name: "Skips channels the target is already in",
+			bots: []BotInfo{
+				{ID: busy, Channels: []string{"a", "b", "c"}},
+				{ID: idle, Channels: []string{"a"}},
+			},
+			tolerance: 1,
+			limit:     10,
+			expected: []Move{
+				{Channel: "b", From: busy, To: idle},
+			},
+		},
+		{
+			name: "Skips fixed channels",
+			bots: []BotInfo{
+				{ID: busy, Channels: []string{"a", "b", "c"}},
+				{ID: idle},
+			},
+			fixed:     map[string]struct{}{"a": {}},
+			tolerance: 1,
+			limit:     10,
+			expected: []Move{
+				{Channel: "b", From: busy, To: idle},
+			},
+		},
+		{
+			name: "Balances other bots when the busiest can't move anything",
+			bots: []BotInfo{
+				{ID: busy, Channels: []string{"a", "b", "c", "d", "e"}},
+				{ID: other, Channels: []string{"f", "g", "h", "i"}},
+				{ID: idle},
+			},
+			fixed:     map[string]struct{}{"a": {}, "b": {}, "c": {}, "d": {}, "e": {}},
+			tolerance: 1,
+			limit:     10,
+			expected: []Move{
+				{Channel: "f", From: other, To: idle},
+				{Channel: "g", From: other, To: idle},
+			},
+		},
+		{
+			name: "Skips cordoned bots",
+			bots: []BotInfo{
+				{ID: busy, Channels: []string{"a", "b", "c"}},
+				{ID: idle, Cordoned: true},
+			},
+			tolerance: 1,
+			limit:     10,
+		},
+		{
+			name: "Skips bots at capacity",
+			bots: []BotInfo{
+				{ID: busy, Channels: []string{"a", "b", "c", "d"}},
+				{ID: idle, Channels: []string{"e"}, MaxChannels: 2},
+			},
+			tolerance: 1,
+			limit:     10,
+			expected: []Move{
+				{Channel: "a", From: busy, To: idle},
+			},
+		},
+		{
+			name: "Skips moves the filter disallows",
+			bots: []BotInfo{
+				{ID: busy, Channels: []string{"a", "b", "c"}},
+				{ID: idle},
+			},
+			tolerance: 1,
+			limit:     10,
+			allowed: func(channel string, _ uuid.UUID, _ BotInfo) bool {
+				return channel != "a"
+			},
+			expected: []Move{
+				{Channel: "b", From: busy, To: idle},
+			},
+		},
+		{
+			name: "Moves grouped channels together",
+			bots: []BotInfo{
+				{ID: busy, Channels: []string{"a", "b", "c", "d", "e"}},
+				{ID: idle},
+			},
+			groups:    map[string][]string{"raid": {"a", "b"}},
+			tolerance: 1,
+			limit:     1,
+			expected: []Move{
+				{Channel: "a", From: busy, To: idle},
+				{Channel: "b", From: busy, To: idle},
+			},
+		},
+		{
+			name: "Skips groups which would leave the bots less balanced",
+			bots: []BotInfo{
+				{ID: busy, Channels: []string{"a", "b", "c"}},
+				{ID: idle},
+			},
+			groups:    map[string][]string{"raid": {"a", "b", "c"}},
+			tolerance: 1,
+			limit:     10,
+		},
+		{
+			name: "Single bot",
+			bots: []BotInfo{
+				{ID: busy, Channels: []string{"a", "b", "c"}},
+			},
+			tolerance: 1,
+			limit:     10,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, planRebalance(tt.bots, tt.fixed, tt.groups, tt.tolerance, tt.limit, tt.allowed))
+		})
+	}
+}
+
+func Test_PlanRebalance(t *testing.T) {
+	one := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	two := uuid.MustParse("00000000-0000-0000-0000-000000000002")
+	three := uuid.MustParse("00000000-0000-0000-0000-000000000003")
+
+	plan := PlanRebalance([]BotInfo{
+		{ID: one, Channels: []string{"a", "b", "c", "d", "e", "f"}},
+		{ID: two, Channels: []string{"g", "h"}},
+		{ID: three},
+	}, nil, 1)
+	require.Equal(t, RebalancePlan{
+		Moves: []Move{
+			{Channel: "a", From: one, To: three},
+			{Channel: "b", From: one, To: three},
+			{Channel: "c", From: one, To: two},
+		},
+		Before: map[uuid.UUID]int{one: 6, two: 2, three: 0},
+		After:  map[uuid.UUID]int{one: 3, two: 3, three: 2},
+	}, plan)
+
+	balanced := PlanRebalance([]BotInfo{
+		{ID: one, Channels: []string{"a"}},
+		{ID: two, Channels: []string{"b"}},
+	}, nil, 1)
+	require.Empty(t, balanced.Moves)
+}
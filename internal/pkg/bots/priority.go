@@ -0,0 +1,115 @@
+package bots
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// WithPriority sets how important a channel is, when bots run out of capacity higher priority channels are placed
+// first and evict lower priority channels back to the pending queue, by default channels have a priority of 0
+func WithPriority(priority int) ChannelOption {
+	return func(c *channelState) {
+		c.priority = priority
+	}
+}
+
+// sortByPriority orders channels from the highest priority to the lowest, keeping the existing order otherwise
+func (s *service) sortByPriority(channels []string) {
+	sort.SliceStable(channels, func(i, j int) bool {
+		return s.channels[channels[i]].priority > s.channels[channels[j]].priority
+	})
+}
+
+// preempt evicts the lowest priority channel from a full bot which could otherwise run the channel, returning the bot
+// Bots are filtered the same way as when placing the channel, and a grouped channel is evicted with the rest of its group
+// on the bot
+// Returns ErrNoCandidates if no full bot is running a lower priority channel which can be evicted
+func (s *service) preempt(channel string, state *channelState) (*botState, error) {
+	if state.isPinned() && !state.hasBot(state.pinned) {
+		// Waiting for the pinned bot rather than short of capacity
+		return nil, ErrNoCandidates
+	}
+	replicas := s.replicaInfos(state, uuid.Nil)
+	candidates := make([]BotInfo, 0, len(s.bots))
+	for _, info := range s.botInfos() {
+		if !state.hasBot(info.ID) && !info.Cordoned && !info.hasCapacity() && s.allows(channel, state, info, replicas) {
+			candidates = append(candidates, info)
+		}
+	}
+	candidates = s.groupCandidates(channel, candidates)
+	candidates = s.versionCandidates(channel, candidates)
+	candidates = s.spreadCandidates(channel, candidates, replicas)
+	var (
+		victims        []string
+		victimPriority int
+		victimBot      *botState
+	)
+	for _, id := range sortedIDs(candidates) {
+		bot := s.bots[id]
+		info := bot.BotInfo()
+		sort.Strings(info.Channels)
+		for _, ch := range info.Channels {
+			if s.sameGroup(channel, ch) {
+				// Evicting the rest of its own group wouldn't make room to run alongside it
+				continue
+			}
+			evicted, priority, ok := s.evictable(ch, info, state.priority)
+			if !ok || evicted[0] != ch {
+				// Can't be evicted, or already considered with the first of its group
+				continue
+			}
+			if victimBot == nil || priority < victimPriority {
+				victims, victimPriority, victimBot = evicted, priority, bot
+			}
+		}
+	}
+	if victimBot == nil {
+		return nil, ErrNoCandidates
+	}
+	for _, victim := range victims {
+		s.logger.Info("preempting channel",
+			zap.String("channel", victim),
+			zap.String("for", channel),
+			zap.String("bot_id", victimBot.id.String()),
+		)
+		s.channels[victim].removeBot(victimBot.id)
+		if err := s.leaveChannel(victimBot, victim); err != nil {
+			s.logger.Warn("failed to leave channel", zap.String("channel", victim), zap.Error(err))
+		}
+		s.enqueue(victim)
+	}
+	return victimBot, nil
+}
+
+// evictable returns the channels which would be evicted from a bot along with a channel, the channel and the rest of
+// its group on the bot in alphabetical order, and the highest priority among them
+// Returns false if any of them has at least the given priority, is pinned to the bot or is migrating
+func (s *service) evictable(channel string, bot BotInfo, priority int) ([]string, int, bool) {
+	evicted := []string{channel}
+	if name, ok := s.groupOf(channel); ok {
+		evicted = evicted[:0]
+		for _, member := range s.groups[name] {
+			for _, ch := range bot.Channels {
+				if ch == member {
+					evicted = append(evicted, member)
+				}
+			}
+		}
+	}
+	highest := 0
+	for i, ch := range evicted {
+		state, ok := s.channels[ch]
+		if !ok || state.priority >= priority || state.pinnedTo(bot.ID) {
+			return nil, 0, false
+		}
+		if _, migrating := s.migrations[ch]; migrating {
+			return nil, 0, false
+		}
+		if i == 0 || state.priority > highest {
+			highest = state.priority
+		}
+	}
+	return evicted, highest, true
+}
@@ -0,0 +1,78 @@
+package bots
+
+import (
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type (
+	// pendingChannel is a channel waiting for a bot with capacity to be placed on
+	pendingChannel struct {
+		channel  string
+		queuedAt time.Time
+	}
+
+	// PendingChannel is a struct containing information about a channel waiting to be placed
+	PendingChannel struct {
+		Channel  string    `json:"channel"`
+		QueuedAt time.Time `json:"queued_at"`
+		// WaitSeconds is how long the channel has been waiting so far
+		WaitSeconds float64 `json:"wait_seconds"`
+		Priority    int     `json:"priority"`
+	}
+)
+
+// enqueue adds a channel to the back of the pending queue if it isn't already waiting
+func (s *service) enqueue(channel string) {
+	if s.isPending(channel) {
+		return
+	}
+	s.logger.Info("queueing channel", zap.String("channel", channel))
+	s.pending = append(s.pending, pendingChannel{
+		channel:  channel,
+		queuedAt: time.Now(),
+	})
+}
+
+// dequeue removes a channel from the pending queue
+func (s *service) dequeue(channel string) {
+	for i, pending := range s.pending {
+		if pending.channel == channel {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *service) isPending(channel string) bool {
+	for _, pending := range s.pending {
+		if pending.channel == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// PendingChannels returns the channels waiting to be placed, in the order they will be placed
+func (s *service) PendingChannels() []PendingChannel {
+	s.chanMux.RLock()
+	defer s.chanMux.RUnlock()
+	now := time.Now()
+	pending := make([]PendingChannel, len(s.pending))
+	for i, p := range s.pending {
+		pending[i] = PendingChannel{
+			Channel:     p.channel,
+			QueuedAt:    p.queuedAt,
+			WaitSeconds: now.Sub(p.queuedAt).Seconds(),
+		}
+		if state, ok := s.channels[p.channel]; ok {
+			pending[i].Priority = state.priority
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return pending[i].Priority > pending[j].Priority
+	})
+	return pending
+}
@@ -0,0 +1,209 @@
+package bots
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// WithRebalanceTolerance sets how many more channels the busiest bot can have than the quietest before channels are
+// moved between them, values below 1 are ignored
+func WithRebalanceTolerance(tolerance int) Option {
+	return func(s *service) {
+		if tolerance >= 1 {
+			s.rebalanceTolerance = tolerance
+		}
+	}
+}
+
+// WithRebalanceBatch sets the maximum number of channels moved at once during a rebalance, and how long to wait
+// before moving the next batch
+func WithRebalanceBatch(size int, interval time.Duration) Option {
+	return func(s *service) {
+		if size >= 1 {
+			s.rebalanceBatchSize = size
+		}
+		s.rebalanceInterval = interval
+	}
+}
+
+// WithAutoRebalance sets whether channels are rebalanced onto bots as they join, enabled by default
+// Rebalances can still be started with Rebalance when disabled
+func WithAutoRebalance(enabled bool) Option {
+	return func(s *service) {
+		s.autoRebalance = enabled
+	}
+}
+
+// rebalance moves a batch of channels from the busiest bots to the quietest, scheduling another batch if the bots
+// are still not balanced
+func (s *service) rebalance() {
+	if s.rebalancing {
+		// A batch is already scheduled which will pick up any changes
+		return
+	}
+	moves := s.planMoves(s.plannedBotInfos(), s.rebalanceBatchSize+1)
+	if len(moves) == 0 {
+		return
+	}
+	batch := moves
+	if len(batch) > s.rebalanceBatchSize {
+		end := s.rebalanceBatchSize
+		// Finish moving a group rather than leaving it split until the next batch
+		for end < len(batch) && s.movesTogether(batch[end-1], batch[end]) {
+			end++
+		}
+		batch = batch[:end]
+	}
+	s.logger.Info("rebalancing channels", zap.Int("moves", len(batch)))
+	for _, m := range batch {
+		if err := s.moveChannel(m); err != nil {
+			s.logger.Warn("failed to move channel", zap.String("channel", m.Channel), zap.Error(err))
+		}
+	}
+	if len(moves) > len(batch) {
+		s.rebalancing = true
+		time.AfterFunc(s.rebalanceInterval, func() {
+			s.mux.Lock()
+			defer s.mux.Unlock()
+			s.chanMux.Lock()
+			defer s.chanMux.Unlock()
+			s.rebalancing = false
+			s.rebalance()
+		})
+	}
+}
+
+// planMoves works out up to limit moves to rebalance the bots
+// Strategies with a preferred bot for each channel move channels back onto it, e.g. with ConsistentHash only the
+// channels which hash to a new bot move onto it, otherwise channels move from the busiest bots to the quietest
+func (s *service) planMoves(infos []BotInfo, limit int) []Move {
+	if preferred, ok := s.placement.(PreferredPlacement); ok {
+		return s.planPreferredMoves(preferred, infos, limit)
+	}
+	return planRebalance(infos, s.fixedChannels(), s.groups, s.rebalanceTolerance, limit, s.allowsRebalance)
+}
+
+// planPreferredMoves moves channels whose bot isn't the one the placement strategy prefers for them now, one replica
+// per channel at a time
+// Grouped channels are placed alongside their group rather than by the strategy, so they're left where they are
+func (s *service) planPreferredMoves(preferred PreferredPlacement, infos []BotInfo, limit int) []Move {
+	fixed := s.fixedChannels()
+	planned := make(map[uuid.UUID]BotInfo, len(infos))
+	for _, info := range infos {
+		planned[info.ID] = info
+	}
+	moves := make([]Move, 0)
+	for _, channel := range sortedChannels(s.channels) {
+		if len(moves) >= limit {
+			break
+		}
+		if _, ok := fixed[channel]; ok {
+			continue
+		}
+		if _, ok := s.groupOf(channel); ok {
+			continue
+		}
+		state := s.channels[channel]
+		for _, from := range state.bots {
+			current, ok := planned[from]
+			if !ok {
+				continue
+			}
+			// Place the channel as if it were new, with the bot running it as one of the candidates
+			current.Channels = without(current.Channels, channel)
+			candidates := []BotInfo{current}
+			for _, id := range sortedIDs(infos) {
+				info := planned[id]
+				if !state.hasBot(id) && info.isSchedulable() && s.allowsRebalance(channel, from, info) {
+					candidates = append(candidates, info)
+				}
+			}
+			to, err := preferred.PreferredBot(channel, s.versionCandidates(channel, candidates))
+			if err != nil || to == from {
+				continue
+			}
+			planned[from] = current
+			target := planned[to]
+			target.Channels = append(append([]string{}, target.Channels...), channel)
+			planned[to] = target
+			moves = append(moves, Move{
+				Channel: channel,
+				From:    from,
+				To:      to,
+			})
+			break
+		}
+	}
+	return moves
+}
+
+// without returns a copy of channels without channel
+func without(channels []string, channel string) []string {
+	rest := make([]string, 0, len(channels))
+	for _, ch := range channels {
+		if ch != channel {
+			rest = append(rest, ch)
+		}
+	}
+	return rest
+}
+
+// plannedBotInfos takes a snapshot of the state of each bot as if every migration in progress has completed
+func (s *service) plannedBotInfos() []BotInfo {
+	infos := s.botInfos()
+	for i, info := range infos {
+		channels := make([]string, 0, len(info.Channels))
+		for _, ch := range info.Channels {
+			if mig, ok := s.migrations[ch]; !ok || mig.from != info.ID {
+				channels = append(channels, ch)
+			}
+		}
+		infos[i].Channels = channels
+	}
+	return infos
+}
+
+// movesTogether returns whether two moves are part of moving a group from one bot to another
+func (s *service) movesTogether(a, b Move) bool {
+	return a.From == b.From && a.To == b.To && s.sameGroup(a.Channel, b.Channel)
+}
+
+// fixedChannels returns the channels which shouldn't be moved by a rebalance, either migrating or pinned
+// Grouped channels can still be moved, but only with the rest of their group
+func (s *service) fixedChannels() map[string]struct{} {
+	fixed := make(map[string]struct{}, len(s.migrations))
+	for ch := range s.migrations {
+		fixed[ch] = struct{}{}
+	}
+	for ch, state := range s.channels {
+		if state.isPinned() {
+			fixed[ch] = struct{}{}
+		}
+	}
+	return fixed
+}
+
+// PlanRebalance works out which channels would be moved to balance the bots, without moving anything
+func (s *service) PlanRebalance() RebalancePlan {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.RLock()
+	defer s.chanMux.RUnlock()
+	infos := s.plannedBotInfos()
+	return rebalancePlan(infos, s.planMoves(infos, math.MaxInt32))
+}
+
+// Rebalance starts moving channels to balance the bots, in batches, returning the plan being applied
+func (s *service) Rebalance() RebalancePlan {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	infos := s.plannedBotInfos()
+	plan := rebalancePlan(infos, s.planMoves(infos, math.MaxInt32))
+	s.rebalance()
+	return plan
+}
@@ -0,0 +1,22 @@
+package bots
+
+// WithHostname sets the host a bot reported it's running on
+func WithHostname(hostname string) BotOption {
+	return func(b *botState) {
+		b.hostname = hostname
+	}
+}
+
+// WithPlatform sets the OS and architecture a bot reported it's running on
+func WithPlatform(platform string) BotOption {
+	return func(b *botState) {
+		b.platform = platform
+	}
+}
+
+// WithProtocolVersion sets the version of the orchestrator protocol a bot speaks
+func WithProtocolVersion(version int) BotOption {
+	return func(b *botState) {
+		b.protocolVersion = version
+	}
+}
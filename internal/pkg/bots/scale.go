@@ -0,0 +1,36 @@
+package bots
+
+import "sort"
+
+// RemovableBots returns the bots which are empty and can be removed without moving any channels, in ID order
+// Bots with channels pinned to them are never removable
+func (s *service) RemovableBots() []BotInfo {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.RLock()
+	defer s.chanMux.RUnlock()
+	return s.removableBots()
+}
+
+func (s *service) removableBots() []BotInfo {
+	removable := make([]BotInfo, 0)
+	for _, info := range s.botInfos() {
+		if len(info.Channels) == 0 && !s.isNeeded(info) {
+			removable = append(removable, info)
+		}
+	}
+	sort.Slice(removable, func(i, j int) bool {
+		return removable[i].ID.String() < removable[j].ID.String()
+	})
+	return removable
+}
+
+// isNeeded returns whether an empty bot is waiting to be given a pinned channel
+func (s *service) isNeeded(info BotInfo) bool {
+	for _, state := range s.channels {
+		if state.pinnedTo(info.ID) {
+			return true
+		}
+	}
+	return false
+}
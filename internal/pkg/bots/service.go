@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/ch629/bot-orchestrator/internal/pkg/proto"
 	"github.com/google/uuid"
@@ -20,35 +21,148 @@ var (
 	ErrInChannel = errors.New("already in channel")
 	// ErrNotInChannel is returned when the orchestrator is not aware of a channel
 	ErrNotInChannel = errors.New("not in channel")
+	// ErrInvalidReplicas is returned when a channel is asked to run on less than one bot
+	ErrInvalidReplicas = errors.New("replicas must be at least 1")
 )
 
 //go:generate mockery --name Service --disable-version-string
 type (
 	// Service is an interface for bot related functionality
 	Service interface {
-		Join(ctx context.Context, id uuid.UUID, botClient proto.BotClient) context.Context
+		Join(ctx context.Context, id uuid.UUID, botClient proto.BotClient, opts ...BotOption) context.Context
 		Leave(id uuid.UUID) error
 		RemoveBot(id uuid.UUID) error
-		JoinChannel(channel string) error
+		JoinChannel(channel string, opts ...ChannelOption) error
 		LeaveChannel(channel string) error
 		BotInfo() []BotInfo
 		ChannelInfo() map[string][]uuid.UUID
 		DanglingChannels() []string
+		ReportLoad(id uuid.UUID, rates map[string]float64) error
+		MoveChannel(channel string, from, to uuid.UUID) error
+		ConfirmJoin(id uuid.UUID, channel string) error
+		AckCommand(id uuid.UUID, commandID string, ackErr error) error
+		Heartbeat(id uuid.UUID) error
+		CanResume(id uuid.UUID, token string) error
+		Resume(ctx context.Context, id uuid.UUID, token string, botClient proto.BotClient, opts ...BotOption) (context.Context, error)
+		Migrations() []MigrationInfo
+		PendingChannels() []PendingChannel
+		Cordon(id uuid.UUID) error
+		Uncordon(id uuid.UUID) error
+		Drain(id uuid.UUID) error
+		DrainStatus(id uuid.UUID) (DrainStatus, error)
+		PlanRebalance() RebalancePlan
+		Rebalance() RebalancePlan
+		UnschedulableChannels() []UnschedulableChannel
+		RemovableBots() []BotInfo
+		SetCanary(version string, percent int, channels []string) error
+		PromoteCanary() error
+		RollbackCanary() error
+		Canary() CanaryInfo
+		SetGroup(name string, channels []string) error
+		DeleteGroup(name string) error
+		Groups() map[string][]string
+		FailedChannels() []FailedChannel
+		Exclude(exclusion Exclusion) error
+		Exclusions() []Exclusion
+		ClearExclusions(filter Exclusion) int
 	}
 
+	// Option configures optional behaviour of the service
+	Option func(s *service)
+
 	service struct {
-		logger   *zap.Logger
+		logger    *zap.Logger
+		placement PlacementStrategy
+		// replicas is the default number of bots each channel runs on
+		replicas int
 		bots     map[uuid.UUID]*botState
-		channels map[string][]uuid.UUID
-		mux      sync.Mutex
-		chanMux  sync.RWMutex
+		channels map[string]*channelState
+		// pending is a FIFO queue of channels waiting for a bot with capacity
+		pending []pendingChannel
+
+		rebalanceTolerance int
+		rebalanceBatchSize int
+		rebalanceInterval  time.Duration
+		autoRebalance      bool
+		// rebalancing is set while another batch of moves is waiting to run
+		rebalancing bool
+
+		// migrations are the channels currently being moved between bots
+		migrations       map[string]*migration
+		migrationTimeout time.Duration
+
+		drainRetryInterval time.Duration
+
+		// commands are the JOINs and LEAVEs waiting to be acknowledged, by command ID
+		commands        map[string]*command
+		ackTimeout      time.Duration
+		commandAttempts int
+		commandBackoff  time.Duration
+		// channelAttempts is how many bots in a row can fail to join a channel before it's given up on, channelBackoff
+		// is how long the channel waits before being placed again once it has failed more than once
+		channelAttempts int
+		channelBackoff  time.Duration
+
+		// exclusions stop channels being placed on bots or accounts until they expire
+		exclusions   map[exclusionKey]*exclusion
+		exclusionTTL time.Duration
+
+		// livenessTimeout is how long a bot which sends heartbeats can go without one, 0 never evicts bots
+		livenessTimeout time.Duration
+
+		// sessions are the bots which disconnected and can still resume, by bot ID
+		sessions           map[uuid.UUID]*session
+		sessionGracePeriod time.Duration
+
+		// pinFallback is how long pinned channels wait for their bot before being placed elsewhere, 0 waits forever
+		pinFallback time.Duration
+
+		// topologyKey is the bot label replicas are spread across
+		topologyKey string
+
+		// canary is the bot version being rolled out, if any
+		canary canary
+
+		// groups are the channels which must be placed on the same bots, by group name
+		groups map[string][]string
+
+		mux     sync.Mutex
+		chanMux sync.RWMutex
 	}
 
 	botState struct {
-		logger     *zap.Logger
-		mux        sync.Mutex
-		id         uuid.UUID
-		channels   map[string]struct{}
+		logger   *zap.Logger
+		mux      sync.Mutex
+		id       uuid.UUID
+		channels map[string]struct{}
+		// rates is the last reported messages per second for each of the bot's channels
+		rates map[string]float64
+		// maxChannels is the most channels the bot can be in, 0 means no limit
+		maxChannels int
+		// cordoned bots have no new channels placed on them
+		cordoned bool
+		// draining bots are having their channels moved to other bots
+		draining bool
+		// drainRetry tries moving a draining bot's channels again after none could be moved
+		drainRetry *time.Timer
+		// labels describe the bot, e.g. region, account, version or platform
+		labels map[string]string
+		// version is the build version the bot reported
+		version string
+		// hostname, platform and protocolVersion describe where the bot runs and how it talks to the orchestrator
+		hostname        string
+		platform        string
+		protocolVersion int
+		// unackedCommands is set for bots which can't acknowledge JOINs or LEAVEs
+		unackedCommands bool
+		// heartbeats is set for bots which send heartbeats, they're evicted if they stop
+		heartbeats    bool
+		lastHeartbeat time.Time
+		livenessTimer *time.Timer
+		// sessionToken lets the bot resume after disconnecting, bots without one can't
+		sessionToken string
+		// removed is set once the bot has been told to stop or evicted, so it can't resume
+		removed    bool
 		client     proto.BotClient
 		ctx        context.Context
 		cancelFunc context.CancelFunc
@@ -58,68 +172,221 @@ type (
 	BotInfo struct {
 		ID       uuid.UUID `json:"id"`
 		Channels []string  `json:"channels"`
+		// Load is the total messages per second reported across all of the bot's channels
+		Load float64 `json:"load"`
+		// MaxChannels is the most channels the bot can be in, 0 means no limit
+		MaxChannels int  `json:"max_channels"`
+		Cordoned    bool `json:"cordoned"`
+		Draining    bool `json:"draining"`
+		// QueueDepth is the number of commands waiting to be sent to the bot
+		QueueDepth int `json:"queue_depth"`
+		// Labels describe the bot, e.g. region, account, version or platform
+		Labels map[string]string `json:"labels"`
+		// Version is the build version the bot reported
+		Version string `json:"version"`
+		// Hostname is the host the bot is running on
+		Hostname string `json:"hostname"`
+		// Platform is the OS and architecture the bot is running on, e.g. linux/amd64
+		Platform string `json:"platform"`
+		// ProtocolVersion is the version of the orchestrator protocol the bot speaks
+		ProtocolVersion int `json:"protocol_version"`
+		// Joining are the channels the bot hasn't acknowledged joining yet, they're included in Channels
+		Joining []string `json:"joining"`
+		// LastHeartbeat is when the bot last sent a heartbeat, or joined if it hasn't sent one yet, nil for bots which
+		// don't send heartbeats
+		LastHeartbeat *time.Time `json:"last_heartbeat"`
 	}
 )
 
-// New creates a new service using a logger
-func New(logger *zap.Logger) Service {
-	return &service{
-		logger:   logger,
-		bots:     make(map[uuid.UUID]*botState),
-		channels: make(map[string][]uuid.UUID),
+// New creates a new service using a logger, channels are placed on the bot with the least channels unless
+// another PlacementStrategy is given
+func New(logger *zap.Logger, opts ...Option) Service {
+	s := &service{
+		logger:    logger,
+		placement: LeastChannels(),
+		replicas:  1,
+		bots:      make(map[uuid.UUID]*botState),
+		channels:  make(map[string]*channelState),
+
+		rebalanceTolerance: 1,
+		rebalanceBatchSize: 10,
+		rebalanceInterval:  5 * time.Second,
+		autoRebalance:      true,
+
+		migrations:       make(map[string]*migration),
+		migrationTimeout: 30 * time.Second,
+
+		drainRetryInterval: 5 * time.Second,
+
+		commands:        make(map[string]*command),
+		ackTimeout:      10 * time.Second,
+		commandAttempts: 3,
+		commandBackoff:  time.Second,
+		channelAttempts: 5,
+		channelBackoff:  10 * time.Second,
+
+		exclusions:   make(map[exclusionKey]*exclusion),
+		exclusionTTL: 24 * time.Hour,
+
+		livenessTimeout: 30 * time.Second,
+
+		sessions:           make(map[uuid.UUID]*session),
+		sessionGracePeriod: 30 * time.Second,
+
+		topologyKey: "zone",
+
+		groups: make(map[string][]string),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WithPlacementStrategy sets the strategy used to pick which bot a channel is assigned to
+func WithPlacementStrategy(strategy PlacementStrategy) Option {
+	return func(s *service) {
+		s.placement = strategy
+	}
+}
+
+// WithReplicationFactor sets the number of distinct bots each channel is placed on, values below 1 are ignored
+func WithReplicationFactor(replicas int) Option {
+	return func(s *service) {
+		if replicas >= 1 {
+			s.replicas = replicas
+		}
 	}
 }
 
 // DanglingChannels returns the channels which have no bots assigned to them
 func (s *service) DanglingChannels() []string {
+	s.chanMux.RLock()
+	defer s.chanMux.RUnlock()
+	return s.danglingChannels()
+}
+
+func (s *service) danglingChannels() []string {
 	channels := make([]string, 0, len(s.channels))
-	for ch, bots := range s.channels {
-		if len(bots) == 0 {
+	for ch, state := range s.channels {
+		if len(state.bots) == 0 {
 			channels = append(channels, ch)
 		}
 	}
 	return channels
 }
 
-// distributeDanglingChannels distributes channels with no bots assigned to bots
-func (s *service) distributeDanglingChannels() {
-	danglingChannels := s.DanglingChannels()
-	if len(danglingChannels) == 0 || len(s.bots) == 0 {
-		return
-	}
-	s.logger.Info("distributing dangling channels")
-
-	bots := s.allBots()
-	for _, channel := range danglingChannels {
-		// Find the bot with the least amount of channels
-		sort.Sort(channelSort(bots))
-		bot := bots[0]
-		if err := bot.JoinChannel(channel); err != nil {
-			s.logger.Warn("failed to join channel", zap.String("channel", channel), zap.Error(err))
+// distributeChannels assigns bots to any channels running on fewer bots than their replication factor
+// Channels are placed highest priority first, then channels in the pending queue before the rest, any channel which
+// still can't be fully placed is queued
+func (s *service) distributeChannels() {
+	channels := make([]string, 0, len(s.pending))
+	for _, pending := range s.pending {
+		channels = append(channels, pending.channel)
+	}
+	for _, channel := range sortedChannels(s.channels) {
+		if !s.isPending(channel) && s.channels[channel].missingReplicas() > 0 {
+			channels = append(channels, channel)
+		}
+	}
+	s.sortByPriority(channels)
+
+	for _, channel := range channels {
+		state := s.channels[channel]
+		if state.givenUp {
+			s.dequeue(channel)
 			continue
 		}
-		s.channels[channel] = []uuid.UUID{bot.id}
+		if len(s.bots) > 0 && !state.backingOff() {
+			s.logger.Info("distributing channel", zap.String("channel", channel), zap.Int("missing_replicas", state.missingReplicas()))
+			if err := s.fillReplicas(channel, state); err != nil && !errors.Is(err, ErrNoCandidates) {
+				s.logger.Warn("failed to distribute channel", zap.String("channel", channel), zap.Error(err))
+			}
+		}
+		if state.missingReplicas() == 0 {
+			s.dequeue(channel)
+		} else {
+			s.enqueue(channel)
+		}
 	}
 }
 
+// sortedChannels returns the names of the channels in alphabetical order
+func sortedChannels(channels map[string]*channelState) []string {
+	names := make([]string, 0, len(channels))
+	for ch := range channels {
+		names = append(names, ch)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fillReplicas joins bots to a channel until it reaches its replication factor, preempting lower priority channels
+// if no bot has capacity
+func (s *service) fillReplicas(channel string, state *channelState) error {
+	for state.missingReplicas() > 0 {
+		bot, err := s.placeChannel(channel, state)
+		if errors.Is(err, ErrNoCandidates) {
+			if preempted, preemptErr := s.preempt(channel, state); preemptErr == nil {
+				bot, err = preempted, nil
+			}
+		}
+		if err != nil {
+			return err
+		}
+		s.joinChannel(bot, channel)
+		state.bots = append(state.bots, bot.id)
+	}
+	return nil
+}
+
 // Join connects a bot to the orchestrator to be controlled
-func (s *service) Join(ctx context.Context, id uuid.UUID, botClient proto.BotClient) context.Context {
-	logger := s.logger.With(zap.String("bot_id", id.String()))
+func (s *service) Join(ctx context.Context, id uuid.UUID, botClient proto.BotClient, opts ...BotOption) context.Context {
 	s.mux.Lock()
 	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	bot := s.addBot(ctx, id, botClient, opts)
+	s.settle(id)
+	bot.logger.Info("bot joined")
+	return bot.ctx
+}
+
+// addBot starts tracking a connected bot
+func (s *service) addBot(ctx context.Context, id uuid.UUID, botClient proto.BotClient, opts []BotOption) *botState {
 	ctx, cancelFunc := context.WithCancel(ctx)
-	s.bots[id] = &botState{
-		logger:     logger,
+	bot := &botState{
+		logger:     s.logger.With(zap.String("bot_id", id.String())),
 		client:     botClient,
 		id:         id,
 		ctx:        ctx,
 		cancelFunc: cancelFunc,
 		channels:   make(map[string]struct{}),
+		rates:      make(map[string]float64),
+	}
+	for _, opt := range opts {
+		opt(bot)
 	}
+	if queued, ok := botClient.(proto.QueuedClient); ok {
+		queued.OnSent(func(commandID string, err error) {
+			s.commandSent(id, commandID, err)
+		})
+	}
+	s.bots[id] = bot
+	s.watchLiveness(bot)
+	return bot
+}
+
+// settle places channels on a newly connected bot
+func (s *service) settle(id uuid.UUID) {
 	// TODO: Should this be async? -> Breaks tests if it is
-	s.distributeDanglingChannels()
-	logger.Info("bot joined")
-	return ctx
+	s.distributeChannels()
+	s.restorePinnedChannels(id)
+	s.rollout()
+	if s.autoRebalance {
+		// Move channels from the existing bots onto the new one
+		s.rebalance()
+	}
 }
 
 // Leave removes a bot from the orchestrator
@@ -127,151 +394,279 @@ func (s *service) Join(ctx context.Context, id uuid.UUID, botClient proto.BotCli
 func (s *service) Leave(id uuid.UUID) error {
 	s.mux.Lock()
 	defer s.mux.Unlock()
-	logger := s.logger.With(zap.String("bot_id", id.String()))
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	return s.leave(id)
+}
+
+func (s *service) leave(id uuid.UUID) error {
 	deletedBot, ok := s.bots[id]
 	if !ok {
 		return ErrBotNotExist
 	}
 	delete(s.bots, id)
-	// Delete channel references to this bot
-	s.chanMux.Lock()
-	defer s.chanMux.Unlock()
-	for ch := range deletedBot.channels {
-		newChannelIDs := make([]uuid.UUID, 0, len(s.channels[ch])-1)
-		for _, old := range s.channels[ch] {
-			if old != id {
-				newChannelIDs = append(newChannelIDs, old)
-			}
-		}
-		s.channels[ch] = newChannelIDs
+	if deletedBot.livenessTimer != nil {
+		deletedBot.livenessTimer.Stop()
 	}
-	s.distributeDanglingChannels()
-	logger.Info("bot left")
+	deletedBot.stopDrainRetry()
+	s.cancelBotCommands(id)
+	if deletedBot.sessionToken != "" && s.sessionGracePeriod > 0 && !deletedBot.removed {
+		s.rollbackMigrationsTo(id)
+		s.suspend(deletedBot)
+		return nil
+	}
+	s.cancelBotMigrations(id)
+	s.releaseChannels(deletedBot)
+	deletedBot.logger.Info("bot left")
 	return nil
 }
 
+// releaseChannels removes a bot which has gone from its channels, placing them on other bots
+func (s *service) releaseChannels(bot *botState) {
+	for ch := range bot.channels {
+		if state, ok := s.channels[ch]; ok {
+			state.removeBot(bot.id)
+		}
+	}
+	s.distributeChannels()
+}
+
 // RemoveBot removes a bot from the orchestrator
 func (s *service) RemoveBot(id uuid.UUID) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
 	bot, ok := s.bots[id]
 	if !ok {
 		return ErrBotNotExist
 	}
 	bot.logger.Info("removing bot")
+	// Removed bots can't resume their session
+	bot.removed = true
 	bot.cancelFunc()
 	return nil
 }
 
-// JoinChannel notifies a bot to connect to a channel, assigned to the bot with the least current channels
-// Returns ErrInChannel if the orchestrator is already in the channel
-// TODO: Come up with a better way to weigh the bots based on message throughput vs just channel count
-// TODO: How do we get this to join on multiple bots to keep high availability?
-func (s *service) JoinChannel(channel string) error {
-	s.chanMux.RLock()
-	_, ok := s.channels[channel]
-	s.chanMux.RUnlock()
-	if ok {
+// JoinChannel notifies bots to connect to a channel, each replica assigned to a distinct bot by the placement
+// strategy
+// Returns ErrInChannel if the orchestrator is already in the channel, or ErrBotNotExist if it's pinned to a bot which
+// doesn't exist without a pin fallback
+func (s *service) JoinChannel(channel string, opts ...ChannelOption) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	if _, ok := s.channels[channel]; ok {
 		return ErrInChannel
 	}
 
-	s.chanMux.Lock()
-	defer s.chanMux.Unlock()
-	if len(s.bots) == 0 {
-		s.channels[channel] = make([]uuid.UUID, 0)
-		return nil
+	state := &channelState{
+		bots:     make([]uuid.UUID, 0),
+		replicas: s.replicas,
+	}
+	for _, opt := range opts {
+		opt(state)
+	}
+	if state.replicas < 1 {
+		return ErrInvalidReplicas
+	}
+	if state.isPinned() && !s.knownBot(state.pinned) && s.pinFallback == 0 {
+		// Without a fallback the channel would wait forever for a bot which may never exist
+		return ErrBotNotExist
 	}
 
-	bots := s.allBots()
-	// Find the bot with the least amount of channels
-	sort.Sort(channelSort(bots))
-	bot := bots[0]
-	if err := bot.JoinChannel(channel); err != nil {
-		return fmt.Errorf("bot.JoinChannel: %w", err)
+	err := s.fillReplicas(channel, state)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrNoCandidates):
+		// Queue the remaining replicas until more bots can take them
+		s.logger.Info("not enough bots for channel", zap.String("channel", channel), zap.Int("missing_replicas", state.missingReplicas()))
+		s.enqueue(channel)
+	case len(state.bots) == 0:
+		return err
+	default:
+		s.logger.Warn("channel is under replicated", zap.String("channel", channel), zap.Error(err))
 	}
-	s.channels[channel] = []uuid.UUID{bot.id}
+	s.channels[channel] = state
 	return nil
 }
 
-func (s *service) allBots() []*botState {
-	bots := make([]*botState, len(s.bots))
-	i := 0
-	for _, bot := range s.bots {
-		bots[i] = bot
-		i++
+// placeChannel asks the placement strategy which bot should run another replica of a channel out of the bots
+// satisfying the channel's placement rules and running the rest of its group, preferring bots on the version the channel
+// should run and in a different topology domain to the other replicas
+// Pinned channels are placed on their pinned bot first
+func (s *service) placeChannel(channel string, state *channelState) (*botState, error) {
+	if bot, err := s.placePinned(channel, state); bot != nil || err != nil {
+		return bot, err
 	}
-	return bots
+	candidates := make([]BotInfo, 0, len(s.bots))
+	replicas := s.replicaInfos(state, uuid.Nil)
+	for _, info := range s.botInfos() {
+		if !state.hasBot(info.ID) && info.isSchedulable() && s.allows(channel, state, info, replicas) {
+			candidates = append(candidates, info)
+		}
+	}
+	candidates = s.groupCandidates(channel, candidates)
+	candidates = s.versionCandidates(channel, candidates)
+	candidates = s.spreadCandidates(channel, candidates, replicas)
+	candidates = state.untried(candidates)
+	id, err := s.placement.Place(channel, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("placement.Place: %w", err)
+	}
+	bot, ok := s.bots[id]
+	if !ok {
+		return nil, ErrBotNotExist
+	}
+	return bot, nil
 }
 
 // LeaveChannel notifies any bot connected to a channel to leave & stops tracking it
 // Returns ErrNotInChannel if the bot isn't in the given channel
 func (s *service) LeaveChannel(channel string) error {
-	s.chanMux.RLock()
-	botIds, ok := s.channels[channel]
-	s.chanMux.RUnlock()
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	state, ok := s.channels[channel]
 	if !ok {
 		return ErrNotInChannel
 	}
-	s.chanMux.Lock()
-	defer s.chanMux.Unlock()
 
 	var err error
-	for _, id := range botIds {
-		bot := s.bots[id]
-		if leaveErr := bot.LeaveChannel(channel); err != nil {
+	for _, id := range state.bots {
+		bot, ok := s.bots[id]
+		if !ok {
+			continue
+		}
+		if leaveErr := s.leaveChannel(bot, channel); leaveErr != nil {
 			err = multierr.Append(err, fmt.Errorf("%s: %w", id, leaveErr))
 		}
 	}
+	s.cancelMigration(channel)
+	s.dequeue(channel)
 	delete(s.channels, channel)
+	// The bots which left may have room for queued channels now
+	s.distributeChannels()
 
 	return err
 }
 
 // BotInfo returns some basic info about all connected bots
 func (s *service) BotInfo() []BotInfo {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.RLock()
+	defer s.chanMux.RUnlock()
+	return s.botInfos()
+}
+
+// botInfos takes a snapshot of the state of each bot
+func (s *service) botInfos() []BotInfo {
 	botInfos := make([]BotInfo, 0, len(s.bots))
 	for _, bot := range s.bots {
-		botInfos = append(botInfos, bot.BotInfo())
+		info := bot.BotInfo()
+		info.Joining = s.joiningChannels(bot.id)
+		botInfos = append(botInfos, info)
 	}
 	return botInfos
 }
 
-// JoinChannel notifies an individual bot to join a channel
-func (b *botState) JoinChannel(channel string) error {
+// ReportLoad records the messages per second a bot is receiving on each of its channels, channels the bot isn't in
+// are ignored
+// Returns ErrBotNotExist if the bot doesn't exist
+func (s *service) ReportLoad(id uuid.UUID, rates map[string]float64) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	bot, ok := s.bots[id]
+	if !ok {
+		return ErrBotNotExist
+	}
+	for _, ch := range bot.reportLoad(rates) {
+		bot.logger.Debug("ignoring load for channel the bot isn't in", zap.String("channel", ch))
+	}
+	return nil
+}
+
+// reportLoad records the messages per second the bot is receiving on each of its channels, returning the channels it
+// reported which it isn't in
+func (b *botState) reportLoad(rates map[string]float64) []string {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	ignored := make([]string, 0)
+	for ch, rate := range rates {
+		// Other replicas report their own rates, and left channels shouldn't build up
+		if _, ok := b.channels[ch]; !ok {
+			ignored = append(ignored, ch)
+			continue
+		}
+		b.rates[ch] = rate
+	}
+	return ignored
+}
+
+// assign records that a channel has been given to the bot
+func (b *botState) assign(channel string) {
 	b.mux.Lock()
 	defer b.mux.Unlock()
 	b.channels[channel] = struct{}{}
-	// TODO: Move logging around
-	defer b.logger.Info("bot joining channel", zap.String("channel", channel))
-	return b.client.SendJoinChannel(channel)
 }
 
-// LeaveChannel notifies an individual bot to leave a channel
-func (b *botState) LeaveChannel(channel string) error {
+// unassign takes a channel away from the bot, returning false if the bot didn't have it
+func (b *botState) unassign(channel string) bool {
 	b.mux.Lock()
 	defer b.mux.Unlock()
 	if _, ok := b.channels[channel]; !ok {
-		return ErrNotInChannel
+		return false
 	}
 	delete(b.channels, channel)
-	// TODO: Move logging around
-	defer b.logger.Info("bot leaving channel", zap.String("channel", channel))
-	return b.client.SendLeaveChannel(channel)
+	delete(b.rates, channel)
+	return true
 }
 
 // BotInfo returns some basic information about an individual bot
 func (b *botState) BotInfo() BotInfo {
 	channels := make([]string, 0, len(b.channels))
+	var load float64
 	for ch := range b.channels {
 		channels = append(channels, ch)
+		load += b.rates[ch]
+	}
+	labels := make(map[string]string, len(b.labels))
+	for k, v := range b.labels {
+		labels[k] = v
+	}
+	info := BotInfo{
+		ID:          b.id,
+		Channels:    channels,
+		Load:        load,
+		MaxChannels: b.maxChannels,
+		Cordoned:    b.cordoned,
+		Draining:    b.draining,
+		Labels:      labels,
+		Version:     b.version,
+
+		Hostname:        b.hostname,
+		Platform:        b.platform,
+		ProtocolVersion: b.protocolVersion,
+	}
+	if b.heartbeats {
+		lastHeartbeat := b.lastHeartbeat
+		info.LastHeartbeat = &lastHeartbeat
 	}
-	return BotInfo{
-		ID:       b.id,
-		Channels: channels,
+	if queued, ok := b.client.(proto.QueuedClient); ok {
+		info.QueueDepth = queued.QueueDepth()
 	}
+	return info
 }
 
 // ChannelInfo returns information about which bots are connected to each channel
 func (s *service) ChannelInfo() map[string][]uuid.UUID {
 	s.chanMux.RLock()
 	defer s.chanMux.RUnlock()
-	return s.channels
+	channels := make(map[string][]uuid.UUID, len(s.channels))
+	for ch, state := range s.channels {
+		channels[ch] = make([]uuid.UUID, len(state.bots))
+		copy(channels[ch], state.bots)
+	}
+	return channels
 }
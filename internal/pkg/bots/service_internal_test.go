@@ -1 +1,44 @@
 package bots
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ch629/bot-orchestrator/internal/pkg/proto/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_serviceDrainRetry(t *testing.T) {
+	s := New(zap.NewNop(), WithDrainRetryInterval(time.Hour)).(*service)
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	id := uuid.New()
+	_ = s.Join(context.Background(), id, mockBotClient)
+	require.NoError(t, s.JoinChannel("foo"))
+	// There's nowhere to move the channel, so the drain is retried later
+	require.NoError(t, s.Drain(id))
+	s.mux.Lock()
+	bot := s.bots[id]
+	first := bot.drainRetry
+	require.NotNil(t, first)
+
+	// Trying again replaces the pending retry rather than stacking another
+	s.chanMux.Lock()
+	s.drainNext(id)
+	s.chanMux.Unlock()
+	require.NotNil(t, bot.drainRetry)
+	require.NotSame(t, first, bot.drainRetry)
+	require.False(t, first.Stop(), "the first retry should already be stopped")
+	second := bot.drainRetry
+	s.mux.Unlock()
+
+	require.NoError(t, s.Uncordon(id))
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	require.Nil(t, bot.drainRetry)
+	require.False(t, second.Stop(), "uncordoning should stop the retry")
+}
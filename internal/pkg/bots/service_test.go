@@ -2,20 +2,25 @@ package bots_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
+	"github.com/ch629/bot-orchestrator/internal/pkg/proto"
 	"github.com/ch629/bot-orchestrator/internal/pkg/proto/mocks"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func Test_ServiceJoin(t *testing.T) {
 	service := bots.New(zap.NewNop())
 	mockBotClient := &mocks.BotClient{}
-	mockBotClient.On("SendJoinChannel", "foo").Return(nil)
+	mockBotClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
 	service.Join(context.Background(), uuid.New(), mockBotClient)
 	service.JoinChannel("foo")
 	botInfo := service.BotInfo()
@@ -30,7 +35,7 @@ func Test_ServiceJoin(t *testing.T) {
 func Test_ServiceDanglingChannels(t *testing.T) {
 	service := bots.New(zap.NewNop())
 	mockBotClient := &mocks.BotClient{}
-	mockBotClient.On("SendJoinChannel", "foo").Return(nil)
+	mockBotClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
 	id := uuid.New()
 	_ = service.Join(context.Background(), id, mockBotClient)
 	service.JoinChannel("foo")
@@ -48,7 +53,7 @@ func Test_ServiceDanglingChannels(t *testing.T) {
 
 func Test_ServiceJoinChannel(t *testing.T) {
 	mockBotClient := &mocks.BotClient{}
-	mockBotClient.On("SendJoinChannel", "foo").Return(nil)
+	mockBotClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
 	service := bots.New(zap.NewNop())
 	// First join should be successful
 	require.NoError(t, service.JoinChannel("foo"))
@@ -65,8 +70,8 @@ func Test_ServiceJoinChannel(t *testing.T) {
 func Test_ServiceLeaveChannel(t *testing.T) {
 	service := bots.New(zap.NewNop())
 	mockBotClient := &mocks.BotClient{}
-	mockBotClient.On("SendJoinChannel", "foo").Return(nil)
-	mockBotClient.On("SendLeaveChannel", "foo").Return(nil)
+	mockBotClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, "foo").Return(nil)
 	service.Join(context.Background(), uuid.New(), mockBotClient)
 	service.JoinChannel("foo")
 	botInfo := service.BotInfo()
@@ -82,8 +87,8 @@ func Test_ServiceLeaveChannel(t *testing.T) {
 func Test_ServiceLeaveMultiple(t *testing.T) {
 	service := bots.New(zap.NewNop())
 	mockBotClient := &mocks.BotClient{}
-	mockBotClient.On("SendJoinChannel", mock.Anything).Return(nil)
-	mockBotClient.On("SendLeaveChannel", mock.Anything).Return(nil)
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
 	id1 := uuid.New()
 	_ = service.Join(context.Background(), id1, mockBotClient)
 	_ = service.Join(context.Background(), uuid.New(), mockBotClient)
@@ -104,3 +109,1110 @@ func Test_ServiceLeaveMissingBot(t *testing.T) {
 	service := bots.New(zap.NewNop())
 	require.ErrorIs(t, service.Leave(uuid.New()), bots.ErrBotNotExist)
 }
+
+func Test_ServicePlacementStrategy(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithPlacementStrategy(bots.RoundRobin()))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	_ = service.Join(context.Background(), uuid.New(), mockBotClient)
+	_ = service.Join(context.Background(), uuid.New(), mockBotClient)
+	require.NoError(t, service.JoinChannel("foo"))
+	require.NoError(t, service.JoinChannel("bar"))
+	// Round robin should give each bot one channel
+	for _, info := range service.BotInfo() {
+		require.Len(t, info.Channels, 1)
+	}
+	mockBotClient.AssertExpectations(t)
+}
+
+func Test_ServiceReportLoad(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithPlacementStrategy(bots.LeastLoaded()))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	busyBot, quietBot := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), busyBot, mockBotClient)
+	require.NoError(t, service.JoinChannel("busy"))
+	_ = service.Join(context.Background(), quietBot, mockBotClient)
+	require.NoError(t, service.JoinChannel("quiet"))
+
+	require.NoError(t, service.ReportLoad(busyBot, map[string]float64{"busy": 100, "unknown": 5}))
+	require.NoError(t, service.ReportLoad(quietBot, map[string]float64{"quiet": 1}))
+	// Bots can't report load for channels they aren't in
+	require.NoError(t, service.ReportLoad(busyBot, map[string]float64{"quiet": 50}))
+	loads := make(map[uuid.UUID]float64)
+	for _, info := range service.BotInfo() {
+		loads[info.ID] = info.Load
+	}
+	require.Equal(t, map[uuid.UUID]float64{busyBot: 100, quietBot: 1}, loads, "untracked channels shouldn't count towards load")
+
+	// Both bots have one channel, but the quiet bot has less load
+	require.NoError(t, service.JoinChannel("new"))
+	for _, info := range service.BotInfo() {
+		if info.ID == quietBot {
+			require.Contains(t, info.Channels, "new")
+		}
+	}
+
+	require.ErrorIs(t, service.ReportLoad(uuid.New(), nil), bots.ErrBotNotExist)
+}
+
+func Test_ServiceReportLoadReplicas(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithReplicationFactor(2))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	id1, id2 := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), id1, mockBotClient)
+	_ = service.Join(context.Background(), id2, mockBotClient)
+	require.NoError(t, service.JoinChannel("foo"))
+
+	// Each replica keeps the rate it reported
+	require.NoError(t, service.ReportLoad(id1, map[string]float64{"foo": 10}))
+	require.NoError(t, service.ReportLoad(id2, map[string]float64{"foo": 20}))
+	require.Equal(t, 10.0, botInfo(t, service, id1).Load)
+	require.Equal(t, 20.0, botInfo(t, service, id2).Load)
+}
+
+func Test_ServiceReplicationFactor(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithReplicationFactor(2))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	id1, id2 := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), id1, mockBotClient)
+	require.NoError(t, service.JoinChannel("foo"))
+	require.Len(t, service.ChannelInfo()["foo"], 1, "only one bot is available")
+
+	// The missing replica should be filled by the next bot to join
+	_ = service.Join(context.Background(), id2, mockBotClient)
+	require.ElementsMatch(t, []uuid.UUID{id1, id2}, service.ChannelInfo()["foo"])
+
+	// Losing a replica should re-fill it straight away with a spare bot
+	id3 := uuid.New()
+	_ = service.Join(context.Background(), id3, mockBotClient)
+	require.NoError(t, service.Leave(id1))
+	require.ElementsMatch(t, []uuid.UUID{id2, id3}, service.ChannelInfo()["foo"])
+	require.Empty(t, service.DanglingChannels())
+}
+
+func Test_ServiceChannelReplicas(t *testing.T) {
+	service := bots.New(zap.NewNop())
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	for i := 0; i < 3; i++ {
+		_ = service.Join(context.Background(), uuid.New(), mockBotClient)
+	}
+	require.NoError(t, service.JoinChannel("foo", bots.WithReplicas(3)))
+	require.NoError(t, service.JoinChannel("bar"))
+	channels := service.ChannelInfo()
+	require.Len(t, channels["foo"], 3)
+	require.Len(t, channels["bar"], 1)
+
+	require.ErrorIs(t, service.JoinChannel("baz", bots.WithReplicas(0)), bots.ErrInvalidReplicas)
+}
+
+func Test_ServiceRebalanceOnJoin(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithRebalanceBatch(1, 10*time.Millisecond))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
+	_ = service.Join(context.Background(), uuid.New(), mockBotClient)
+	for _, ch := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, service.JoinChannel(ch))
+	}
+
+	newBot := uuid.New()
+	_ = service.Join(context.Background(), newBot, mockBotClient)
+	channelCounts := func() map[uuid.UUID]int {
+		counts := make(map[uuid.UUID]int)
+		for _, info := range service.BotInfo() {
+			counts[info.ID] = len(info.Channels)
+		}
+		return counts
+	}
+	require.Equal(t, 1, channelCounts()[newBot], "only the first batch should be moved straight away")
+	require.Eventually(t, func() bool {
+		// Moves only complete once the new bot confirms it has joined
+		for _, migration := range service.Migrations() {
+			require.NoError(t, service.ConfirmJoin(migration.To, migration.Channel))
+		}
+		counts := channelCounts()
+		return counts[newBot] == 2 && len(service.Migrations()) == 0
+	}, time.Second, 10*time.Millisecond, "the next batch should balance the bots")
+	for _, count := range channelCounts() {
+		require.Equal(t, 2, count)
+	}
+	require.Empty(t, service.DanglingChannels())
+}
+
+func Test_ServiceMoveChannel(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithMigrationTimeout(time.Hour))
+	fromClient, toClient := &mocks.BotClient{}, &mocks.BotClient{}
+	fromClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
+	toClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
+	from, to := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), from, fromClient)
+	require.NoError(t, service.JoinChannel("foo"))
+	_ = service.Join(context.Background(), to, toClient)
+
+	require.NoError(t, service.MoveChannel("foo", from, to))
+	// Both bots stay in the channel until the move is confirmed
+	require.ElementsMatch(t, []uuid.UUID{from, to}, service.ChannelInfo()["foo"])
+	require.Len(t, service.Migrations(), 1)
+	require.ErrorIs(t, service.MoveChannel("foo", from, uuid.New()), bots.ErrMigrating)
+	fromClient.AssertNotCalled(t, "SendLeaveChannel", mock.Anything, "foo")
+
+	fromClient.On("SendLeaveChannel", mock.Anything, "foo").Return(nil)
+	require.NoError(t, service.ConfirmJoin(to, "foo"))
+	require.Equal(t, []uuid.UUID{to}, service.ChannelInfo()["foo"])
+	require.Empty(t, service.Migrations())
+	fromClient.AssertExpectations(t)
+	toClient.AssertExpectations(t)
+
+	require.ErrorIs(t, service.MoveChannel("bar", from, to), bots.ErrNotInChannel)
+	require.ErrorIs(t, service.MoveChannel("foo", to, to), bots.ErrBotInChannel)
+}
+
+func Test_ServiceMoveChannelTimeout(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithMigrationTimeout(10*time.Millisecond))
+	fromClient, toClient := &mocks.BotClient{}, &mocks.BotClient{}
+	fromClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
+	toClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
+	toClient.On("SendLeaveChannel", mock.Anything, "foo").Return(nil)
+	from, to := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), from, fromClient)
+	require.NoError(t, service.JoinChannel("foo"))
+	_ = service.Join(context.Background(), to, toClient)
+
+	require.NoError(t, service.MoveChannel("foo", from, to))
+	// The target never confirms, so it should be told to leave and the source kept
+	require.Eventually(t, func() bool {
+		return len(service.Migrations()) == 0
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, []uuid.UUID{from}, service.ChannelInfo()["foo"])
+	fromClient.AssertNotCalled(t, "SendLeaveChannel", mock.Anything, "foo")
+	toClient.AssertExpectations(t)
+}
+
+func Test_ServiceCapacity(t *testing.T) {
+	service := bots.New(zap.NewNop())
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
+	fullBot := uuid.New()
+	_ = service.Join(context.Background(), fullBot, mockBotClient, bots.WithMaxChannels(1))
+	require.NoError(t, service.JoinChannel("foo"))
+	require.NoError(t, service.JoinChannel("bar"))
+	require.NoError(t, service.JoinChannel("baz"))
+
+	// The bot is full so the other channels wait in order
+	pending := service.PendingChannels()
+	require.Len(t, pending, 2)
+	require.Equal(t, "bar", pending[0].Channel)
+	require.Equal(t, "baz", pending[1].Channel)
+	require.Equal(t, 1, service.BotInfo()[0].MaxChannels)
+
+	// Freeing up capacity should place the first queued channel
+	require.NoError(t, service.LeaveChannel("foo"))
+	require.Equal(t, []string{"bar"}, service.BotInfo()[0].Channels)
+	require.Len(t, service.PendingChannels(), 1)
+
+	// A new bot should take the rest of the queue
+	_ = service.Join(context.Background(), uuid.New(), mockBotClient)
+	require.Empty(t, service.PendingChannels())
+	require.Empty(t, service.DanglingChannels())
+}
+
+func Test_ServiceCordon(t *testing.T) {
+	service := bots.New(zap.NewNop())
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	id := uuid.New()
+	_ = service.Join(context.Background(), id, mockBotClient)
+	require.NoError(t, service.Cordon(id))
+	require.NoError(t, service.JoinChannel("foo"))
+	require.Equal(t, []string{"foo"}, service.DanglingChannels(), "cordoned bots shouldn't be given channels")
+	require.True(t, service.BotInfo()[0].Cordoned)
+
+	require.NoError(t, service.Uncordon(id))
+	require.Empty(t, service.DanglingChannels())
+	require.ErrorIs(t, service.Cordon(uuid.New()), bots.ErrBotNotExist)
+}
+
+func Test_ServiceDrain(t *testing.T) {
+	service := bots.New(zap.NewNop())
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
+	drained, other := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), drained, mockBotClient)
+	require.NoError(t, service.JoinChannel("bar"))
+	require.NoError(t, service.JoinChannel("foo"))
+	_ = service.Join(context.Background(), other, mockBotClient, bots.WithMaxChannels(2))
+
+	require.NoError(t, service.Drain(drained))
+	// Channels are moved one at a time, each waiting for the new bot to confirm
+	for _, channel := range []string{"bar", "foo"} {
+		migrations := service.Migrations()
+		require.Len(t, migrations, 1)
+		require.Equal(t, channel, migrations[0].Channel)
+		require.Equal(t, other, migrations[0].To)
+		status, err := service.DrainStatus(drained)
+		require.NoError(t, err)
+		require.False(t, status.Complete)
+		require.NoError(t, service.ConfirmJoin(other, channel))
+	}
+
+	status, err := service.DrainStatus(drained)
+	require.NoError(t, err)
+	require.Equal(t, bots.DrainStatus{ID: drained, Draining: true, Complete: true}, status)
+	channels := service.ChannelInfo()
+	require.Equal(t, []uuid.UUID{other}, channels["foo"])
+	require.Equal(t, []uuid.UUID{other}, channels["bar"])
+}
+
+// preferredBot places channels with the wrapped strategy but prefers one bot for every channel
+type preferredBot struct {
+	bots.PlacementStrategy
+	id uuid.UUID
+}
+
+func (p preferredBot) PreferredBot(channel string, candidates []bots.BotInfo) (uuid.UUID, error) {
+	for _, candidate := range candidates {
+		if candidate.ID == p.id {
+			return p.id, nil
+		}
+	}
+	return p.Place(channel, candidates)
+}
+
+func Test_ServicePreferredPlacement(t *testing.T) {
+	first, preferred := uuid.New(), uuid.New()
+	service := bots.New(zap.NewNop(),
+		bots.WithPlacementStrategy(preferredBot{PlacementStrategy: bots.LeastChannels(), id: preferred}),
+		bots.WithAutoRebalance(false),
+	)
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	_ = service.Join(context.Background(), first, mockBotClient)
+	for _, ch := range []string{"a", "b", "c"} {
+		require.NoError(t, service.JoinChannel(ch))
+	}
+	_ = service.Join(context.Background(), preferred, mockBotClient)
+
+	// Every channel moves to the bot the strategy prefers rather than evening out the bots
+	plan := service.PlanRebalance()
+	require.Equal(t, map[uuid.UUID]int{first: 0, preferred: 3}, plan.After)
+}
+
+func Test_ServiceRebalance(t *testing.T) {
+	service := bots.New(zap.NewNop())
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
+	busy, idle := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), busy, mockBotClient)
+	for _, ch := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, service.JoinChannel(ch))
+	}
+	_ = service.Join(context.Background(), idle, mockBotClient)
+	for _, migration := range service.Migrations() {
+		require.NoError(t, service.ConfirmJoin(migration.To, migration.Channel))
+	}
+	require.Empty(t, service.PlanRebalance().Moves, "the new bot should already be balanced")
+
+	// Draining moves everything back off the new bot, uncordoning should plan to give it channels again
+	require.NoError(t, service.Drain(idle))
+	for len(service.Migrations()) > 0 {
+		migration := service.Migrations()[0]
+		require.NoError(t, service.ConfirmJoin(migration.To, migration.Channel))
+	}
+	require.NoError(t, service.Uncordon(idle))
+	plan := service.PlanRebalance()
+	require.Len(t, plan.Moves, 2)
+	require.Equal(t, map[uuid.UUID]int{busy: 4, idle: 0}, plan.Before)
+	require.Equal(t, map[uuid.UUID]int{busy: 2, idle: 2}, plan.After)
+	require.Empty(t, service.Migrations(), "planning shouldn't move anything")
+
+	require.Equal(t, plan, service.Rebalance())
+	require.Len(t, service.Migrations(), 2)
+	require.Empty(t, service.PlanRebalance().Moves, "channels being moved shouldn't be planned again")
+}
+
+func Test_ServicePinnedChannel(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithPinFallback(50*time.Millisecond), bots.WithMigrationTimeout(time.Hour))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
+	pinned, other := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), other, mockBotClient)
+	_ = service.Join(context.Background(), pinned, mockBotClient)
+	require.NoError(t, service.JoinChannel("foo"))
+	require.NoError(t, service.JoinChannel("bar", bots.WithPinnedBot(pinned)))
+	require.Equal(t, []uuid.UUID{pinned}, service.ChannelInfo()["bar"])
+
+	// Pinned channels are never moved off their bot
+	require.ErrorIs(t, service.MoveChannel("bar", pinned, other), bots.ErrPinned)
+	require.NoError(t, service.Drain(pinned))
+	for len(service.Migrations()) > 0 {
+		migration := service.Migrations()[0]
+		require.NoError(t, service.ConfirmJoin(migration.To, migration.Channel))
+	}
+	status, err := service.DrainStatus(pinned)
+	require.NoError(t, err)
+	require.True(t, status.Complete)
+	require.NoError(t, service.Uncordon(pinned))
+
+	// The channel waits for the pinned bot before falling back to another bot
+	require.NoError(t, service.Leave(pinned))
+	require.Equal(t, []string{"bar"}, service.DanglingChannels())
+	require.Eventually(t, func() bool {
+		return len(service.DanglingChannels()) == 0
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, []uuid.UUID{other}, service.ChannelInfo()["bar"])
+
+	// Once the pinned bot is back the channel is moved back onto it
+	_ = service.Join(context.Background(), pinned, mockBotClient)
+	migrations := service.Migrations()
+	require.Len(t, migrations, 1)
+	require.Equal(t, bots.MigrationInfo{Channel: "bar", From: other, To: pinned, StartedAt: migrations[0].StartedAt}, migrations[0])
+	require.NoError(t, service.ConfirmJoin(pinned, "bar"))
+	require.Equal(t, []uuid.UUID{pinned}, service.ChannelInfo()["bar"])
+	require.Empty(t, service.PlanRebalance().Moves)
+}
+
+func Test_ServicePinnedChannelWaits(t *testing.T) {
+	service := bots.New(zap.NewNop())
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	pinned := uuid.New()
+	_ = service.Join(context.Background(), uuid.New(), mockBotClient)
+	_ = service.Join(context.Background(), pinned, mockBotClient)
+	require.NoError(t, service.JoinChannel("foo", bots.WithPinnedBot(pinned)))
+	require.NoError(t, service.Leave(pinned))
+	require.Equal(t, []string{"foo"}, service.DanglingChannels(), "without a fallback the channel waits for its bot")
+
+	_ = service.Join(context.Background(), pinned, mockBotClient)
+	require.Equal(t, []uuid.UUID{pinned}, service.ChannelInfo()["foo"])
+}
+
+func Test_ServicePinnedToUnknownBot(t *testing.T) {
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	pinned := uuid.New()
+
+	// Without a fallback the channel would never be placed
+	service := bots.New(zap.NewNop())
+	_ = service.Join(context.Background(), uuid.New(), mockBotClient)
+	require.ErrorIs(t, service.JoinChannel("foo", bots.WithPinnedBot(pinned)), bots.ErrBotNotExist)
+	require.NotContains(t, service.ChannelInfo(), "foo")
+
+	// With a fallback it waits for the bot to join before being placed elsewhere
+	service = bots.New(zap.NewNop(), bots.WithPinFallback(50*time.Millisecond))
+	_ = service.Join(context.Background(), uuid.New(), mockBotClient)
+	require.NoError(t, service.JoinChannel("foo", bots.WithPinnedBot(pinned)))
+	require.Eventually(t, func() bool {
+		return len(service.DanglingChannels()) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func Test_ServiceNodeSelector(t *testing.T) {
+	service := bots.New(zap.NewNop())
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	plain, modbot := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), plain, mockBotClient, bots.WithLabels(map[string]string{"account": "bot"}))
+	require.NoError(t, service.JoinChannel("foo", bots.WithNodeSelector(map[string]string{"account": "modbot"})))
+	require.Equal(t, []bots.UnschedulableChannel{
+		{Channel: "foo", MissingReplicas: 1, Reason: "no bot has labels account=modbot"},
+	}, service.UnschedulableChannels())
+
+	_ = service.Join(context.Background(), modbot, mockBotClient, bots.WithLabels(map[string]string{"account": "modbot"}))
+	require.Equal(t, []uuid.UUID{modbot}, service.ChannelInfo()["foo"])
+	require.Empty(t, service.UnschedulableChannels())
+	require.ErrorIs(t, service.MoveChannel("foo", modbot, plain), bots.ErrRulesNotMet)
+	require.Empty(t, service.PlanRebalance().Moves, "channels shouldn't be moved onto bots breaking their rules")
+}
+
+func Test_ServiceAntiAffinity(t *testing.T) {
+	service := bots.New(zap.NewNop())
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	first, second, third := uuid.New(), uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), first, mockBotClient, bots.WithLabels(map[string]string{"host": "a"}))
+	_ = service.Join(context.Background(), second, mockBotClient, bots.WithLabels(map[string]string{"host": "a"}))
+	require.NoError(t, service.JoinChannel("foo", bots.WithReplicas(2), bots.WithAntiAffinity("host")))
+	require.Len(t, service.ChannelInfo()["foo"], 1, "replicas shouldn't share a host")
+	require.Equal(t, []bots.UnschedulableChannel{
+		{Channel: "foo", MissingReplicas: 1, Reason: "no bot has a different host to the channel's other replicas"},
+	}, service.UnschedulableChannels())
+
+	_ = service.Join(context.Background(), third, mockBotClient, bots.WithLabels(map[string]string{"host": "b"}))
+	require.Contains(t, service.ChannelInfo()["foo"], third)
+	require.Len(t, service.ChannelInfo()["foo"], 2)
+	require.Empty(t, service.UnschedulableChannels())
+}
+
+func Test_ServiceTopologySpread(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	service := bots.New(zap.New(core))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	zoneA, otherZoneA, zoneB := uuid.New(), uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), zoneA, mockBotClient, bots.WithLabels(map[string]string{"zone": "a"}))
+	_ = service.Join(context.Background(), otherZoneA, mockBotClient, bots.WithLabels(map[string]string{"zone": "a"}))
+	// The only other bot is in the same zone, so the replicas are co-located
+	require.NoError(t, service.JoinChannel("foo", bots.WithReplicas(2)))
+	require.ElementsMatch(t, []uuid.UUID{zoneA, otherZoneA}, service.ChannelInfo()["foo"])
+	require.Equal(t, 1, logs.FilterMessage("co-locating channel replicas, no other topology domain is available").Len())
+
+	_ = service.Join(context.Background(), zoneB, mockBotClient, bots.WithLabels(map[string]string{"zone": "b"}))
+	require.NoError(t, service.JoinChannel("bar", bots.WithReplicas(2)))
+	replicas := service.ChannelInfo()["bar"]
+	require.Len(t, replicas, 2)
+	require.Contains(t, replicas, zoneB, "replicas should be spread across zones")
+	require.Equal(t, 1, logs.FilterMessage("co-locating channel replicas, no other topology domain is available").Len())
+}
+
+func Test_ServiceBinPack(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithPlacementStrategy(bots.BinPack()), bots.WithAutoRebalance(false))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	first, second, third := uuid.New(), uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{first, second, third} {
+		_ = service.Join(context.Background(), id, mockBotClient, bots.WithMaxChannels(2))
+	}
+	for _, ch := range []string{"a", "b", "c"} {
+		require.NoError(t, service.JoinChannel(ch))
+	}
+	require.Empty(t, service.Migrations(), "packed channels shouldn't be rebalanced")
+
+	// Three channels fit on two bots, leaving one empty
+	removable := service.RemovableBots()
+	require.Len(t, removable, 1)
+	require.Empty(t, removable[0].Channels)
+
+	// Bots with a channel waiting to be pinned to them aren't removable
+	require.NoError(t, service.Cordon(removable[0].ID))
+	require.NoError(t, service.JoinChannel("d", bots.WithPinnedBot(removable[0].ID)))
+	require.Empty(t, service.RemovableBots())
+}
+
+func Test_ServiceCanary(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithAutoRebalance(false), bots.WithMigrationTimeout(time.Hour))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
+	stable, canary := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), stable, mockBotClient, bots.WithVersion("v1"))
+	require.NoError(t, service.JoinChannel("bar"))
+	require.NoError(t, service.JoinChannel("foo"))
+	_ = service.Join(context.Background(), canary, mockBotClient, bots.WithVersion("v2"))
+	confirmAll := func() {
+		for len(service.Migrations()) > 0 {
+			migration := service.Migrations()[0]
+			require.NoError(t, service.ConfirmJoin(migration.To, migration.Channel))
+		}
+	}
+
+	require.ErrorIs(t, service.PromoteCanary(), bots.ErrNoCanary)
+	require.ErrorIs(t, service.SetCanary("v2", 101, nil), bots.ErrInvalidPercent)
+
+	// Only the listed channel moves onto the new version, joining before the old bot leaves
+	require.NoError(t, service.SetCanary("v2", 0, []string{"foo"}))
+	require.Equal(t, []bots.MigrationInfo{{Channel: "foo", From: stable, To: canary, StartedAt: service.Migrations()[0].StartedAt}}, service.Migrations())
+	confirmAll()
+	require.Equal(t, []uuid.UUID{canary}, service.ChannelInfo()["foo"])
+	require.Equal(t, []uuid.UUID{stable}, service.ChannelInfo()["bar"])
+	require.Equal(t, bots.CanaryInfo{Version: "v2", Percent: 0, Channels: []string{"foo"}, Placed: 1}, service.Canary())
+
+	// New channels are placed on the version they should run
+	require.NoError(t, service.JoinChannel("baz"))
+	require.Equal(t, []uuid.UUID{stable}, service.ChannelInfo()["baz"])
+
+	require.NoError(t, service.RollbackCanary())
+	confirmAll()
+	require.Equal(t, 0, service.Canary().Placed)
+
+	require.NoError(t, service.PromoteCanary())
+	confirmAll()
+	require.Equal(t, 3, service.Canary().Placed)
+	for _, bot := range service.BotInfo() {
+		if bot.ID == canary {
+			require.Equal(t, "v2", bot.Version)
+			require.Len(t, bot.Channels, 3)
+		}
+	}
+}
+
+func Test_ServicePriority(t *testing.T) {
+	service := bots.New(zap.NewNop())
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
+	first, second := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), first, mockBotClient, bots.WithMaxChannels(1))
+	_ = service.Join(context.Background(), second, mockBotClient, bots.WithMaxChannels(1))
+	require.NoError(t, service.JoinChannel("low"))
+	require.NoError(t, service.JoinChannel("medium", bots.WithPriority(5)))
+
+	// Bots are full, so the partner channel evicts the lowest priority channel
+	require.NoError(t, service.JoinChannel("partner", bots.WithPriority(10)))
+	channels := service.ChannelInfo()
+	require.Len(t, channels["partner"], 1)
+	require.Len(t, channels["medium"], 1)
+	require.Empty(t, channels["low"])
+	pending := service.PendingChannels()
+	require.Len(t, pending, 1)
+	require.Equal(t, "low", pending[0].Channel)
+
+	// Channels can't evict others of the same or higher priority
+	require.NoError(t, service.JoinChannel("other"))
+	require.Len(t, service.PendingChannels(), 2)
+
+	// Losing a bot places the highest priority channels first
+	require.NoError(t, service.Leave(channels["partner"][0]))
+	channels = service.ChannelInfo()
+	require.Len(t, channels["partner"], 1)
+	require.Empty(t, channels["medium"])
+	require.Equal(t, []string{"medium", "low", "other"}, func() []string {
+		var names []string
+		for _, p := range service.PendingChannels() {
+			names = append(names, p.Channel)
+		}
+		return names
+	}())
+}
+
+func Test_ServicePreemptsFullBots(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithAutoRebalance(false))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
+	full, roomy := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), full, mockBotClient, bots.WithMaxChannels(1))
+	require.NoError(t, service.SetGroup("raid", []string{"foo", "bar"}))
+	require.NoError(t, service.JoinChannel("foo"))
+	_ = service.Join(context.Background(), roomy, mockBotClient, bots.WithMaxChannels(2))
+	require.NoError(t, service.JoinChannel("low"))
+	require.Equal(t, []uuid.UUID{roomy}, service.ChannelInfo()["low"])
+
+	// bar has to run alongside foo, the other bot has room so evicting from it wouldn't help
+	require.NoError(t, service.JoinChannel("bar", bots.WithPriority(10)))
+	channels := service.ChannelInfo()
+	require.Equal(t, []uuid.UUID{full}, channels["foo"])
+	require.Equal(t, []uuid.UUID{roomy}, channels["low"])
+	require.Empty(t, channels["bar"])
+	require.Len(t, service.PendingChannels(), 1)
+	mockBotClient.AssertNotCalled(t, "SendLeaveChannel", mock.Anything, mock.Anything)
+}
+
+func Test_ServicePreemptsGroups(t *testing.T) {
+	service := bots.New(zap.NewNop())
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
+	id := uuid.New()
+	_ = service.Join(context.Background(), id, mockBotClient, bots.WithMaxChannels(2))
+	require.NoError(t, service.SetGroup("raid", []string{"foo", "bar"}))
+	require.NoError(t, service.JoinChannel("foo"))
+	require.NoError(t, service.JoinChannel("bar"))
+
+	// The whole group is evicted rather than leaving part of it behind
+	require.NoError(t, service.JoinChannel("partner", bots.WithPriority(10)))
+	channels := service.ChannelInfo()
+	require.Equal(t, []uuid.UUID{id}, channels["partner"])
+	require.Empty(t, channels["foo"])
+	require.Empty(t, channels["bar"])
+}
+
+func Test_ServiceGroups(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithMigrationTimeout(time.Hour))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
+	first, second := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), first, mockBotClient)
+	_ = service.Join(context.Background(), second, mockBotClient)
+	require.NoError(t, service.JoinChannel("foo"))
+	require.NoError(t, service.JoinChannel("bar"))
+	require.NotEqual(t, service.ChannelInfo()["foo"], service.ChannelInfo()["bar"])
+
+	// Grouping channels running apart moves them together
+	require.NoError(t, service.SetGroup("raid", []string{"foo", "bar", "baz"}))
+	require.ErrorIs(t, service.SetGroup("mods", []string{"foo"}), bots.ErrInGroup)
+	require.ErrorIs(t, service.SetGroup("mods", nil), bots.ErrEmptyGroup)
+	require.Len(t, service.Migrations(), 1)
+	migration := service.Migrations()[0]
+	require.NoError(t, service.ConfirmJoin(migration.To, migration.Channel))
+	channels := service.ChannelInfo()
+	require.Equal(t, channels["foo"], channels["bar"])
+	host := channels["foo"][0]
+
+	// New members join the rest of the group, even when another bot has fewer channels
+	require.NoError(t, service.JoinChannel("baz"))
+	require.Equal(t, []uuid.UUID{host}, service.ChannelInfo()["baz"])
+	require.NoError(t, service.JoinChannel("qux"))
+	require.NotEqual(t, []uuid.UUID{host}, service.ChannelInfo()["qux"])
+	require.Empty(t, service.PlanRebalance().Moves, "grouped channels shouldn't be split up by a rebalance")
+
+	// Losing the bot places the whole group together elsewhere
+	require.NoError(t, service.Leave(host))
+	channels = service.ChannelInfo()
+	require.Len(t, channels["foo"], 1)
+	require.Equal(t, channels["foo"], channels["bar"])
+	require.Equal(t, channels["foo"], channels["baz"])
+
+	// A rebalance moves the whole group onto a new bot
+	joined := uuid.New()
+	_ = service.Join(context.Background(), joined, mockBotClient)
+	migrations := service.Migrations()
+	require.Len(t, migrations, 3)
+	for _, migration := range migrations {
+		require.Equal(t, joined, migration.To)
+		require.NoError(t, service.ConfirmJoin(migration.To, migration.Channel))
+	}
+	channels = service.ChannelInfo()
+	require.Equal(t, []uuid.UUID{joined}, channels["foo"])
+	require.Equal(t, channels["foo"], channels["bar"])
+	require.Equal(t, channels["foo"], channels["baz"])
+
+	require.Equal(t, map[string][]string{"raid": {"bar", "baz", "foo"}}, service.Groups())
+	require.NoError(t, service.DeleteGroup("raid"))
+	require.ErrorIs(t, service.DeleteGroup("raid"), bots.ErrGroupNotExist)
+}
+
+func Test_ServiceAckCommand(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithAckTimeout(time.Hour))
+	commandIDs := make(chan string, 1)
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil).Run(func(args mock.Arguments) {
+		commandIDs <- args.String(0)
+	})
+	id := uuid.New()
+	_ = service.Join(context.Background(), id, mockBotClient)
+	require.NoError(t, service.JoinChannel("foo"))
+
+	// The channel is assigned to the bot but it hasn't joined until it acks
+	botInfo := service.BotInfo()
+	require.Equal(t, []string{"foo"}, botInfo[0].Channels)
+	require.Equal(t, []string{"foo"}, botInfo[0].Joining)
+
+	commandID := <-commandIDs
+	require.ErrorIs(t, service.AckCommand(uuid.New(), commandID, nil), bots.ErrBotNotExist)
+	require.ErrorIs(t, service.AckCommand(id, uuid.NewString(), nil), bots.ErrCommandNotExist)
+	require.NoError(t, service.AckCommand(id, commandID, nil))
+	require.Empty(t, service.BotInfo()[0].Joining)
+	require.ErrorIs(t, service.AckCommand(id, commandID, nil), bots.ErrCommandNotExist)
+}
+
+func Test_ServiceReassignsFailedJoins(t *testing.T) {
+	service := bots.New(zap.NewNop(),
+		bots.WithAckTimeout(time.Hour),
+		bots.WithCommandRetries(2, time.Millisecond),
+		bots.WithAutoRebalance(false),
+	)
+	commandIDs := make(chan string, 2)
+	failingClient, healthyClient := &mocks.BotClient{}, &mocks.BotClient{}
+	failingClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil).Run(func(args mock.Arguments) {
+		commandIDs <- args.String(0)
+	})
+	failingClient.On("SendLeaveChannel", mock.Anything, "foo").Return(nil)
+	healthyClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
+	failing, healthy := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), failing, failingClient)
+	require.NoError(t, service.JoinChannel("foo"))
+	_ = service.Join(context.Background(), healthy, healthyClient)
+
+	// A failed JOIN is sent again with the same ID
+	first := <-commandIDs
+	require.NoError(t, service.AckCommand(failing, first, errors.New("banned")))
+	select {
+	case second := <-commandIDs:
+		require.Equal(t, first, second)
+	case <-time.After(time.Second):
+		require.Fail(t, "JOIN was never retried")
+	}
+
+	// Once it has run out of attempts the channel moves to another bot, the failing bot isn't excluded from it as it
+	// wasn't banned
+	require.NoError(t, service.AckCommand(failing, first, errors.New("banned")))
+	require.Equal(t, []uuid.UUID{healthy}, service.ChannelInfo()["foo"])
+	failingClient.AssertCalled(t, "SendLeaveChannel", mock.Anything, "foo")
+	healthyClient.AssertExpectations(t)
+	require.Empty(t, service.Exclusions())
+}
+
+func Test_ServiceRetriesUnacknowledgedJoins(t *testing.T) {
+	service := bots.New(zap.NewNop(),
+		bots.WithAckTimeout(10*time.Millisecond),
+		bots.WithCommandRetries(2, time.Millisecond),
+	)
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, "foo").Return(nil)
+	_ = service.Join(context.Background(), uuid.New(), mockBotClient)
+	require.NoError(t, service.JoinChannel("foo"))
+
+	// The bot never acks, so the JOIN is sent twice, the bot isn't excluded so it's tried again as there's no other bot,
+	// then the channel backs off
+	require.Eventually(t, func() bool {
+		return len(service.PendingChannels()) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Empty(t, service.ChannelInfo()["foo"])
+	require.Empty(t, service.Exclusions())
+	mockBotClient.AssertNumberOfCalls(t, "SendJoinChannel", 4)
+}
+
+func Test_ServiceAckTimeoutStartsOnSend(t *testing.T) {
+	service := bots.New(zap.NewNop(),
+		bots.WithAckTimeout(100*time.Millisecond),
+		bots.WithCommandRetries(1, time.Millisecond),
+		bots.WithAutoRebalance(false),
+	)
+	id := uuid.New()
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		commandID := args.String(0)
+		time.AfterFunc(10*time.Millisecond, func() {
+			_ = service.AckCommand(id, commandID, nil)
+		})
+	})
+	// The last JOIN is sent well after the ack timeout, but the bot acks each one soon after it's sent
+	dispatcher := proto.NewDispatcher(zap.NewNop(), proto.Limit{Rate: 10, Burst: 1}, proto.Limit{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_ = service.Join(ctx, id, dispatcher.Wrap(ctx, mockBotClient, ""))
+	for _, channel := range []string{"a", "b", "c"} {
+		require.NoError(t, service.JoinChannel(channel))
+	}
+
+	require.Eventually(t, func() bool {
+		info := botInfo(t, service, id)
+		return info.QueueDepth == 0 && len(info.Joining) == 0
+	}, time.Second, 10*time.Millisecond)
+	require.ElementsMatch(t, []string{"a", "b", "c"}, botInfo(t, service, id).Channels)
+	require.Empty(t, service.FailedChannels())
+	require.Empty(t, service.PendingChannels())
+	mockBotClient.AssertNumberOfCalls(t, "SendJoinChannel", 3)
+}
+
+func Test_ServiceUnacknowledgedCommands(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithAckTimeout(10*time.Millisecond))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
+	id := uuid.New()
+	_ = service.Join(context.Background(), id, mockBotClient, bots.WithUnacknowledgedCommands())
+	require.NoError(t, service.JoinChannel("foo"))
+
+	// The JOIN is done once it's sent, so it never times out
+	require.Eventually(t, func() bool {
+		return len(botInfo(t, service, id).Joining) == 0
+	}, time.Second, time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	require.Equal(t, []uuid.UUID{id}, service.ChannelInfo()["foo"])
+	mockBotClient.AssertNumberOfCalls(t, "SendJoinChannel", 1)
+}
+
+func Test_ServiceTypedJoinFailures(t *testing.T) {
+	service := bots.New(zap.NewNop(),
+		bots.WithAckTimeout(time.Hour),
+		bots.WithCommandRetries(3, time.Hour),
+		bots.WithAutoRebalance(false),
+	)
+	commandIDs := make(chan string, 1)
+	bannedClient, healthyClient := &mocks.BotClient{}, &mocks.BotClient{}
+	bannedClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		commandIDs <- args.String(0)
+	})
+	bannedClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
+	healthyClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		commandIDs <- args.String(0)
+	})
+	banned, healthy := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), banned, bannedClient)
+	require.NoError(t, service.JoinChannel("foo"))
+	_ = service.Join(context.Background(), healthy, healthyClient)
+
+	// A banned bot isn't sent the JOIN again, the channel moves straight to another bot
+	require.NoError(t, service.AckCommand(banned, <-commandIDs, fmt.Errorf("modbot: %w", bots.ErrBanned)))
+	require.Equal(t, []uuid.UUID{healthy}, service.ChannelInfo()["foo"])
+	bannedClient.AssertNumberOfCalls(t, "SendJoinChannel", 1)
+	require.NoError(t, service.AckCommand(healthy, <-commandIDs, nil))
+	require.Empty(t, service.FailedChannels())
+
+	// No bot can join a channel which doesn't exist, so it's given up on
+	require.NoError(t, service.JoinChannel("bar"))
+	require.NoError(t, service.AckCommand(banned, <-commandIDs, bots.ErrChannelNotFound))
+	failed := service.FailedChannels()
+	require.Len(t, failed, 1)
+	require.Equal(t, "bar", failed[0].Channel)
+	require.Equal(t, "channel_not_found", failed[0].Reason)
+	require.Equal(t, 1, failed[0].Attempts)
+	require.Empty(t, service.ChannelInfo()["bar"])
+	require.Empty(t, service.PendingChannels())
+
+	// Leaving the channel forgets about the failure
+	require.NoError(t, service.LeaveChannel("bar"))
+	require.Empty(t, service.FailedChannels())
+}
+
+func Test_ServiceChannelBackoff(t *testing.T) {
+	service := bots.New(zap.NewNop(),
+		bots.WithAckTimeout(time.Hour),
+		bots.WithCommandRetries(1, 0),
+		bots.WithChannelRetries(3, 50*time.Millisecond),
+		bots.WithAutoRebalance(false),
+	)
+	type join struct {
+		bot       uuid.UUID
+		commandID string
+	}
+	joins := make(chan join, 1)
+	for i := 0; i < 3; i++ {
+		id := uuid.New()
+		mockBotClient := &mocks.BotClient{}
+		mockBotClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil).Run(func(args mock.Arguments) {
+			joins <- join{bot: id, commandID: args.String(0)}
+		})
+		mockBotClient.On("SendLeaveChannel", mock.Anything, "foo").Return(nil)
+		_ = service.Join(context.Background(), id, mockBotClient)
+	}
+	require.NoError(t, service.JoinChannel("foo"))
+	next := func() join {
+		select {
+		case j := <-joins:
+			return j
+		case <-time.After(time.Second):
+			require.Fail(t, "channel was never placed")
+			return join{}
+		}
+	}
+
+	// The first failure moves the channel to another bot straight away
+	first := next()
+	require.NoError(t, service.AckCommand(first.bot, first.commandID, errors.New("timed out")))
+	second := next()
+	require.NotEqual(t, first.bot, second.bot)
+
+	// Later ones wait for the backoff before trying another bot
+	require.NoError(t, service.AckCommand(second.bot, second.commandID, errors.New("timed out")))
+	require.Empty(t, service.ChannelInfo()["foo"])
+	require.Len(t, service.PendingChannels(), 1)
+	third := next()
+	require.NotEqual(t, first.bot, third.bot)
+	require.NotEqual(t, second.bot, third.bot)
+
+	// Once it has failed on enough bots it's given up on
+	require.NoError(t, service.AckCommand(third.bot, third.commandID, errors.New("timed out")))
+	failed := service.FailedChannels()
+	require.Len(t, failed, 1)
+	require.Equal(t, "error", failed[0].Reason)
+	require.Equal(t, "timed out", failed[0].Error)
+	require.Equal(t, 3, failed[0].Attempts)
+	require.Empty(t, service.PendingChannels())
+}
+
+func Test_ServiceExclusions(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithAckTimeout(time.Hour), bots.WithAutoRebalance(false))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, "foo").Return(nil)
+	modbot, other := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), modbot, mockBotClient, bots.WithLabels(map[string]string{"account": "modbot"}))
+	_ = service.Join(context.Background(), other, mockBotClient, bots.WithLabels(map[string]string{"account": "other"}))
+	require.ErrorIs(t, service.Exclude(bots.Exclusion{Channel: "foo"}), bots.ErrInvalidExclusion)
+	require.ErrorIs(t, service.Exclude(bots.Exclusion{Channel: "foo", BotID: &other, Account: "modbot"}), bots.ErrInvalidExclusion)
+
+	// Excluded accounts aren't given the channel
+	require.NoError(t, service.Exclude(bots.Exclusion{Channel: "foo", Account: "modbot", Reason: "banned"}))
+	require.NoError(t, service.JoinChannel("foo"))
+	require.Equal(t, []uuid.UUID{other}, service.ChannelInfo()["foo"])
+
+	// Excluding the bot running the channel moves it off
+	require.NoError(t, service.Exclude(bots.Exclusion{Channel: "foo", BotID: &other}))
+	require.Empty(t, service.ChannelInfo()["foo"])
+	require.Len(t, service.PendingChannels(), 1)
+	exclusions := service.Exclusions()
+	require.Len(t, exclusions, 2)
+	require.True(t, exclusions[0].ExpiresAt.After(time.Now().Add(time.Hour)))
+
+	// Clearing an exclusion lets the channel be placed again
+	require.Equal(t, 1, service.ClearExclusions(bots.Exclusion{Account: "modbot"}))
+	require.Equal(t, []uuid.UUID{modbot}, service.ChannelInfo()["foo"])
+	require.Equal(t, 1, service.ClearExclusions(bots.Exclusion{}))
+	require.Empty(t, service.Exclusions())
+}
+
+func Test_ServiceBannedAccountExcluded(t *testing.T) {
+	service := bots.New(zap.NewNop(),
+		bots.WithAckTimeout(time.Hour),
+		bots.WithAutoRebalance(false),
+		bots.WithExclusionTTL(50*time.Millisecond),
+	)
+	commandIDs := make(chan string, 1)
+	bannedClient, otherClient := &mocks.BotClient{}, &mocks.BotClient{}
+	bannedClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil).Run(func(args mock.Arguments) {
+		commandIDs <- args.String(0)
+	})
+	bannedClient.On("SendLeaveChannel", mock.Anything, "foo").Return(nil)
+	otherClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
+	banned, sameAccount, otherAccount := uuid.New(), uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), banned, bannedClient, bots.WithLabels(map[string]string{"account": "modbot"}))
+	require.NoError(t, service.JoinChannel("foo"))
+	_ = service.Join(context.Background(), sameAccount, otherClient, bots.WithLabels(map[string]string{"account": "modbot"}))
+	_ = service.Join(context.Background(), otherAccount, otherClient, bots.WithLabels(map[string]string{"account": "other"}))
+
+	// A ban excludes every bot using the account, not just the one which reported it
+	require.NoError(t, service.AckCommand(banned, <-commandIDs, bots.ErrBanned))
+	require.Equal(t, []uuid.UUID{otherAccount}, service.ChannelInfo()["foo"])
+	exclusions := service.Exclusions()
+	require.Len(t, exclusions, 1)
+	require.Equal(t, "modbot", exclusions[0].Account)
+	require.Equal(t, "banned", exclusions[0].Reason)
+	require.ErrorIs(t, service.MoveChannel("foo", otherAccount, sameAccount), bots.ErrRulesNotMet)
+
+	// Exclusions expire after the TTL
+	require.Eventually(t, func() bool {
+		return len(service.Exclusions()) == 0
+	}, time.Second, 10*time.Millisecond)
+	require.NoError(t, service.MoveChannel("foo", otherAccount, sameAccount))
+}
+
+func Test_ServiceLiveness(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithLivenessTimeout(100*time.Millisecond), bots.WithSessionGracePeriod(time.Hour))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
+	silent, quiet := uuid.New(), uuid.New()
+	ctx := service.Join(context.Background(), silent, mockBotClient, bots.WithHeartbeats(), bots.WithSessionToken("token"))
+	require.NoError(t, service.JoinChannel("foo"))
+	// Bots which don't send heartbeats are never evicted
+	_ = service.Join(context.Background(), quiet, mockBotClient)
+	require.ErrorIs(t, service.Heartbeat(uuid.New()), bots.ErrBotNotExist)
+
+	// Heartbeats keep the bot alive past the timeout
+	joinedAt := botInfo(t, service, silent).LastHeartbeat
+	require.NotNil(t, joinedAt)
+	for i := 0; i < 4; i++ {
+		time.Sleep(20 * time.Millisecond)
+		require.NoError(t, service.Heartbeat(silent))
+	}
+	require.True(t, botInfo(t, service, silent).LastHeartbeat.After(*joinedAt))
+	require.Nil(t, botInfo(t, service, quiet).LastHeartbeat)
+
+	// Once they stop the bot is evicted and its channels placed elsewhere straight away, it can't resume
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		require.Fail(t, "bot was never evicted")
+	}
+	require.Eventually(t, func() bool {
+		return len(service.BotInfo()) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, []uuid.UUID{quiet}, service.ChannelInfo()["foo"])
+	require.ErrorIs(t, service.CanResume(silent, "token"), bots.ErrSessionNotExist)
+}
+
+func Test_ServiceResume(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithSessionGracePeriod(time.Hour))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	id := uuid.New()
+	_ = service.Join(context.Background(), id, mockBotClient, bots.WithSessionToken("secret"))
+	require.NoError(t, service.JoinChannel("foo"))
+	require.NoError(t, service.JoinChannel("bar"))
+	require.ErrorIs(t, service.CanResume(id, "secret"), bots.ErrSessionInUse)
+
+	// The bot's channels are kept for it while it's disconnected
+	require.NoError(t, service.Leave(id))
+	require.Empty(t, service.BotInfo())
+	require.Equal(t, []uuid.UUID{id}, service.ChannelInfo()["foo"])
+	require.ErrorIs(t, service.CanResume(id, "wrong"), bots.ErrInvalidSessionToken)
+	require.ErrorIs(t, service.CanResume(uuid.New(), "secret"), bots.ErrSessionNotExist)
+	require.NoError(t, service.CanResume(id, "secret"))
+
+	// Resuming sends the bot its channels again
+	resumedClient := &mocks.BotClient{}
+	resumedClient.On("SendJoinChannel", mock.Anything, "bar").Return(nil).Once()
+	resumedClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil).Once()
+	_, err := service.Resume(context.Background(), id, "secret", resumedClient)
+	require.NoError(t, err)
+	resumedClient.AssertExpectations(t)
+	require.ElementsMatch(t, []string{"bar", "foo"}, botInfo(t, service, id).Channels)
+	_, err = service.Resume(context.Background(), id, "secret", resumedClient)
+	require.ErrorIs(t, err, bots.ErrSessionInUse)
+
+	// Removed bots can't come back
+	require.NoError(t, service.RemoveBot(id))
+	require.NoError(t, service.Leave(id))
+	require.ErrorIs(t, service.CanResume(id, "secret"), bots.ErrSessionNotExist)
+	require.Empty(t, service.ChannelInfo()["foo"])
+}
+
+func Test_ServiceResumeDuringMigration(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithSessionGracePeriod(time.Hour), bots.WithAutoRebalance(false))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil)
+	mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil)
+	from, to := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), from, mockBotClient, bots.WithSessionToken("from"))
+	require.NoError(t, service.JoinChannel("foo"))
+	require.NoError(t, service.JoinChannel("bar"))
+	_ = service.Join(context.Background(), to, mockBotClient, bots.WithSessionToken("to"))
+
+	// The move onto a bot which disconnects is rolled back, leaving the channel where it was
+	require.NoError(t, service.MoveChannel("foo", from, to))
+	require.NoError(t, service.Leave(to))
+	require.Empty(t, service.Migrations())
+	require.Equal(t, []uuid.UUID{from}, service.ChannelInfo()["foo"])
+	resumedClient := &mocks.BotClient{}
+	resumedClient.On("SendLeaveChannel", mock.Anything, "foo").Return(nil).Once()
+	_, err := service.Resume(context.Background(), to, "to", resumedClient)
+	require.NoError(t, err)
+	resumedClient.AssertExpectations(t)
+	require.Equal(t, []uuid.UUID{from}, service.ChannelInfo()["foo"])
+	require.Empty(t, botInfo(t, service, to).Channels)
+
+	// The move off a bot which disconnects carries on, and the bot leaves the channel once it resumes
+	resumedClient.On("SendJoinChannel", mock.Anything, "bar").Return(nil)
+	require.NoError(t, service.MoveChannel("bar", from, to))
+	require.NoError(t, service.Leave(from))
+	require.Len(t, service.Migrations(), 1)
+	require.NoError(t, service.ConfirmJoin(to, "bar"))
+	require.Equal(t, []uuid.UUID{to}, service.ChannelInfo()["bar"])
+	resumedClient = &mocks.BotClient{}
+	resumedClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil).Once()
+	resumedClient.On("SendLeaveChannel", mock.Anything, "bar").Return(nil).Once()
+	_, err = service.Resume(context.Background(), from, "from", resumedClient)
+	require.NoError(t, err)
+	resumedClient.AssertExpectations(t)
+	require.Equal(t, []uuid.UUID{from}, service.ChannelInfo()["foo"])
+	require.Equal(t, []uuid.UUID{to}, service.ChannelInfo()["bar"])
+}
+
+func Test_ServiceSessionExpires(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithSessionGracePeriod(50*time.Millisecond))
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, "foo").Return(nil)
+	id, other := uuid.New(), uuid.New()
+	_ = service.Join(context.Background(), id, mockBotClient, bots.WithSessionToken("secret"))
+	require.NoError(t, service.JoinChannel("foo"))
+	_ = service.Join(context.Background(), other, mockBotClient)
+
+	require.NoError(t, service.Leave(id))
+	require.Equal(t, []uuid.UUID{id}, service.ChannelInfo()["foo"])
+	// Once the grace period expires the channels are placed on other bots
+	require.Eventually(t, func() bool {
+		return len(service.ChannelInfo()["foo"]) == 1 && service.ChannelInfo()["foo"][0] == other
+	}, time.Second, 10*time.Millisecond)
+	require.ErrorIs(t, service.CanResume(id, "secret"), bots.ErrSessionNotExist)
+}
+
+// botInfo returns the info of a single bot
+func botInfo(t *testing.T, service bots.Service, id uuid.UUID) bots.BotInfo {
+	for _, info := range service.BotInfo() {
+		if info.ID == id {
+			return info
+		}
+	}
+	require.Fail(t, "bot not found", id.String())
+	return bots.BotInfo{}
+}
@@ -0,0 +1,124 @@
+package bots
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/ch629/bot-orchestrator/internal/pkg/proto"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrSessionNotExist is returned when resuming a bot which hasn't disconnected, or whose grace period has expired
+	ErrSessionNotExist = errors.New("session does not exist")
+	// ErrInvalidSessionToken is returned when resuming a bot with the wrong session token
+	ErrInvalidSessionToken = errors.New("invalid session token")
+	// ErrSessionInUse is returned when resuming a bot which is still connected
+	ErrSessionInUse = errors.New("bot is still connected")
+)
+
+// session is a bot which disconnected, its channels are kept for it until the grace period expires
+type session struct {
+	bot   *botState
+	timer *time.Timer
+}
+
+// WithSessionGracePeriod sets how long a disconnected bot's channels are kept for it to resume before they're placed
+// on other bots, 0 places them straight away
+func WithSessionGracePeriod(grace time.Duration) Option {
+	return func(s *service) {
+		s.sessionGracePeriod = grace
+	}
+}
+
+// WithSessionToken sets the token a bot must present to resume after disconnecting
+func WithSessionToken(token string) BotOption {
+	return func(b *botState) {
+		b.sessionToken = token
+	}
+}
+
+// suspend keeps a disconnected bot's channels for it until the grace period expires
+func (s *service) suspend(bot *botState) {
+	bot.logger.Info("bot disconnected, waiting for it to resume", zap.Duration("grace_period", s.sessionGracePeriod))
+	sess := &session{bot: bot}
+	sess.timer = time.AfterFunc(s.sessionGracePeriod, func() {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		s.chanMux.Lock()
+		defer s.chanMux.Unlock()
+		if s.sessions[bot.id] != sess {
+			// Already resumed
+			return
+		}
+		delete(s.sessions, bot.id)
+		bot.logger.Info("session expired, bot left")
+		s.releaseChannels(bot)
+	})
+	s.sessions[bot.id] = sess
+}
+
+// CanResume returns whether a disconnected bot can resume its session with a token
+// Returns ErrSessionInUse if the bot is still connected, ErrSessionNotExist if it can't be resumed, or
+// ErrInvalidSessionToken if the token is wrong
+func (s *service) CanResume(id uuid.UUID, token string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	_, err := s.resumable(id, token)
+	return err
+}
+
+func (s *service) resumable(id uuid.UUID, token string) (*session, error) {
+	if _, ok := s.bots[id]; ok {
+		return nil, ErrSessionInUse
+	}
+	sess, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotExist
+	}
+	if subtle.ConstantTimeCompare([]byte(sess.bot.sessionToken), []byte(token)) != 1 {
+		return nil, ErrInvalidSessionToken
+	}
+	return sess, nil
+}
+
+// Resume reconnects a bot within its grace period, keeping its ID and the channels it was running
+// The bot is sent a JOIN for each of its channels which are still running, and a LEAVE for any it was taken out of
+// Returns the same errors as CanResume
+func (s *service) Resume(ctx context.Context, id uuid.UUID, token string, botClient proto.BotClient, opts ...BotOption) (context.Context, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.chanMux.Lock()
+	defer s.chanMux.Unlock()
+	sess, err := s.resumable(id, token)
+	if err != nil {
+		return nil, err
+	}
+	sess.timer.Stop()
+	delete(s.sessions, id)
+	bot := s.addBot(ctx, id, botClient, append([]BotOption{WithSessionToken(token)}, opts...))
+	channels := make([]string, 0, len(sess.bot.channels))
+	for ch := range sess.bot.channels {
+		channels = append(channels, ch)
+	}
+	sort.Strings(channels)
+	for _, ch := range channels {
+		if state, ok := s.channels[ch]; ok && state.hasBot(id) {
+			s.joinChannel(bot, ch)
+			continue
+		}
+		// The channel was left while the bot was away, it may still be in it
+		s.sendCommand(bot, &command{
+			id:      uuid.NewString(),
+			bot:     id,
+			channel: ch,
+		})
+	}
+	s.settle(id)
+	bot.logger.Info("bot resumed", zap.Int("channels", len(bot.channels)))
+	return bot.ctx, nil
+}
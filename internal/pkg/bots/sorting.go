@@ -1,6 +1,6 @@
 package bots
 
-type channelSort []*botState
+type channelSort []BotInfo
 
 func (b channelSort) Len() int {
 	return len(b)
@@ -11,5 +11,25 @@ func (b channelSort) Swap(i, j int) {
 }
 
 func (b channelSort) Less(i, j int) bool {
-	return len(b[i].channels) < len(b[j].channels)
+	if len(b[i].Channels) == len(b[j].Channels) {
+		return b[i].ID.String() < b[j].ID.String()
+	}
+	return len(b[i].Channels) < len(b[j].Channels)
+}
+
+type loadSort []BotInfo
+
+func (b loadSort) Len() int {
+	return len(b)
+}
+
+func (b loadSort) Swap(i, j int) {
+	b[i], b[j] = b[j], b[i]
+}
+
+func (b loadSort) Less(i, j int) bool {
+	if b[i].Load == b[j].Load {
+		return channelSort(b).Less(i, j)
+	}
+	return b[i].Load < b[j].Load
 }
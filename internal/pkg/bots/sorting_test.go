@@ -9,24 +9,39 @@ import (
 )
 
 func Test_channelSort(t *testing.T) {
-	botOne := &botState{
-		id: uuid.New(),
-		channels: map[string]struct{}{
-			"one": {},
-		},
+	botOne := BotInfo{
+		ID:       uuid.New(),
+		Channels: []string{"one"},
 	}
-	botTwo := &botState{
-		id: uuid.New(),
-		channels: map[string]struct{}{
-			"one": {},
-			"two": {},
-		},
+	botTwo := BotInfo{
+		ID:       uuid.New(),
+		Channels: []string{"one", "two"},
 	}
-	originalBots := []*botState{botOne, botTwo}
-	sortedBots := []*botState{botOne, botTwo}
+	originalBots := []BotInfo{botOne, botTwo}
+	sortedBots := []BotInfo{botTwo, botOne}
 	sort.Sort(channelSort(sortedBots))
-	require.Equal(t, []*botState{
+	require.Equal(t, []BotInfo{
 		originalBots[0],
 		originalBots[1],
-	}, []*botState(sortedBots))
+	}, []BotInfo(sortedBots))
+}
+
+func Test_loadSort(t *testing.T) {
+	quiet := BotInfo{
+		ID:       uuid.New(),
+		Channels: []string{"one", "two"},
+		Load:     1,
+	}
+	busy := BotInfo{
+		ID:       uuid.New(),
+		Channels: []string{"three"},
+		Load:     50,
+	}
+	empty := BotInfo{
+		ID:   uuid.New(),
+		Load: 1,
+	}
+	sortedBots := []BotInfo{busy, quiet, empty}
+	sort.Sort(loadSort(sortedBots))
+	require.Equal(t, []BotInfo{empty, quiet, busy}, []BotInfo(sortedBots))
 }
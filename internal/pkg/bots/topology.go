@@ -0,0 +1,44 @@
+package bots
+
+import (
+	"go.uber.org/zap"
+)
+
+// WithTopologyKey sets the bot label used to spread each channel's replicas across distinct topology domains, e.g.
+// "zone" or "host", by default bots are spread by "zone"
+func WithTopologyKey(key string) Option {
+	return func(s *service) {
+		s.topologyKey = key
+	}
+}
+
+// spreadCandidates narrows the candidates for a channel to those in a topology domain none of its replicas are in
+// Replicas are co-located with a warning if no other domain is available
+func (s *service) spreadCandidates(channel string, candidates, replicas []BotInfo) []BotInfo {
+	used := make(map[string]struct{}, len(replicas))
+	for _, replica := range replicas {
+		if domain := replica.Labels[s.topologyKey]; domain != "" {
+			used[domain] = struct{}{}
+		}
+	}
+	if len(used) == 0 {
+		return candidates
+	}
+	spread := make([]BotInfo, 0, len(candidates))
+	for _, candidate := range candidates {
+		domain := candidate.Labels[s.topologyKey]
+		if _, ok := used[domain]; domain != "" && !ok {
+			spread = append(spread, candidate)
+		}
+	}
+	if len(spread) > 0 {
+		return spread
+	}
+	if len(candidates) > 0 {
+		s.logger.Warn("co-locating channel replicas, no other topology domain is available",
+			zap.String("channel", channel),
+			zap.String("topology_key", s.topologyKey),
+		)
+	}
+	return candidates
+}
@@ -1,12 +1,33 @@
 package proto
 
-import "github.com/ch629/bot-orchestrator/pkg/proto"
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ch629/bot-orchestrator/pkg/proto"
+)
+
+// ErrUnsupported is returned when sending a command the bot's stream can't carry
+var ErrUnsupported = errors.New("command not supported by JoinStream")
+
+// Config is the configuration sent to a bot
+type Config struct {
+	// MetricsInterval is how often the bot should report the message rates on its channels
+	MetricsInterval time.Duration
+	// HeartbeatInterval is how often the bot should send a heartbeat
+	HeartbeatInterval time.Duration
+}
 
 // BotClient is a client to send messages to an individual bot
 //go:generate mockery --name BotClient --disable-version-string
 type BotClient interface {
-	SendJoinChannel(channel string) error
-	SendLeaveChannel(channel string) error
+	// SendJoinChannel sends a JOIN which the bot acknowledges with commandID
+	SendJoinChannel(commandID, channel string) error
+	// SendLeaveChannel sends a LEAVE which the bot acknowledges with commandID
+	SendLeaveChannel(commandID, channel string) error
+	SendConfig(config Config) error
+	SendShutdown(reason string) error
 }
 
 // NewClient builds a new BotClient using a protobuf stream
@@ -22,18 +43,86 @@ type botClient struct {
 	stream proto.Orchestrator_JoinStreamServer
 }
 
-// SendJoinChannel sends a Join Channel request to a bot
-func (c *botClient) SendJoinChannel(channel string) error {
+// SendJoinChannel sends a Join Channel request to a bot, JoinStream bots confirm the join by channel rather than
+// commandID
+func (c *botClient) SendJoinChannel(_, channel string) error {
 	return c.stream.Send(&proto.StreamPayload{
 		Type:    proto.StreamPayload_JOIN,
 		Channel: channel,
 	})
 }
 
-// SendLeaveChannel sends a Leave Channel request to a bot
-func (c *botClient) SendLeaveChannel(channel string) error {
+// SendLeaveChannel sends a Leave Channel request to a bot, JoinStream bots can't acknowledge leaves
+func (c *botClient) SendLeaveChannel(_, channel string) error {
 	return c.stream.Send(&proto.StreamPayload{
 		Type:    proto.StreamPayload_LEAVE,
 		Channel: channel,
 	})
 }
+
+// SendConfig returns ErrUnsupported, JoinStream can only carry joins and leaves
+func (c *botClient) SendConfig(Config) error {
+	return ErrUnsupported
+}
+
+// SendShutdown returns ErrUnsupported, JoinStream bots are shut down by closing the stream
+func (c *botClient) SendShutdown(string) error {
+	return ErrUnsupported
+}
+
+// NewStreamClient builds a new BotClient using a bidirectional protobuf stream
+func NewStreamClient(stream proto.Orchestrator_ConnectServer) BotClient {
+	return &streamClient{
+		stream: stream,
+	}
+}
+
+// streamClient is a wrapper around the bidirectional gRPC stream to communicate with bot pods directly
+type streamClient struct {
+	// mux stops messages being sent on the stream concurrently
+	mux    sync.Mutex
+	stream proto.Orchestrator_ConnectServer
+}
+
+// SendJoinChannel sends a Join Channel command to a bot
+func (c *streamClient) SendJoinChannel(commandID, channel string) error {
+	return c.send(&proto.OrchestratorMessage{
+		Payload: &proto.OrchestratorMessage_Join{Join: &proto.JoinCommand{
+			Channel:   channel,
+			CommandId: commandID,
+		}},
+	})
+}
+
+// SendLeaveChannel sends a Leave Channel command to a bot
+func (c *streamClient) SendLeaveChannel(commandID, channel string) error {
+	return c.send(&proto.OrchestratorMessage{
+		Payload: &proto.OrchestratorMessage_Leave{Leave: &proto.LeaveCommand{
+			Channel:   channel,
+			CommandId: commandID,
+		}},
+	})
+}
+
+// SendConfig sends the configuration a bot should use
+func (c *streamClient) SendConfig(config Config) error {
+	return c.send(&proto.OrchestratorMessage{
+		Payload: &proto.OrchestratorMessage_Config{Config: &proto.Config{
+			MetricsIntervalMs:   config.MetricsInterval.Milliseconds(),
+			HeartbeatIntervalMs: config.HeartbeatInterval.Milliseconds(),
+		}},
+	})
+}
+
+// SendShutdown tells a bot to disconnect and stop
+func (c *streamClient) SendShutdown(reason string) error {
+	return c.send(&proto.OrchestratorMessage{
+		Payload: &proto.OrchestratorMessage_Shutdown{Shutdown: &proto.Shutdown{Reason: reason}},
+	})
+}
+
+func (c *streamClient) send(msg *proto.OrchestratorMessage) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.stream.Send(msg)
+}
@@ -0,0 +1,202 @@
+package proto
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+type (
+	// Limit is a token bucket allowing Rate JOINs per second, with bursts of up to Burst JOINs at once
+	// A zero Limit doesn't limit JOINs at all, a Burst below 1 is treated as 1
+	Limit struct {
+		Rate  float64
+		Burst int
+	}
+
+	// Dispatcher rate limits the JOINs sent to bots, both per bot and per account so that bots sharing an account share
+	// the platform's limit
+	Dispatcher struct {
+		logger     *zap.Logger
+		perBot     Limit
+		perAccount Limit
+		mux        sync.Mutex
+		accounts   map[string]*rate.Limiter
+	}
+
+	// QueuedClient is a BotClient which queues commands until they can be sent
+	QueuedClient interface {
+		BotClient
+		// QueueDepth returns the number of commands waiting to be sent
+		QueueDepth() int
+		// OnSent calls sent with each command's ID once it has been sent to the bot, with the error if it couldn't be
+		OnSent(sent func(commandID string, err error))
+	}
+
+	rateLimitedClient struct {
+		logger   *zap.Logger
+		client   BotClient
+		limiters []*rate.Limiter
+		mux      sync.Mutex
+		queue    []command
+		notify   chan struct{}
+		sent     func(commandID string, err error)
+	}
+
+	command struct {
+		id      string
+		join    bool
+		channel string
+	}
+)
+
+// NewDispatcher creates a Dispatcher with limits applied to each bot and to each account
+func NewDispatcher(logger *zap.Logger, perBot, perAccount Limit) *Dispatcher {
+	return &Dispatcher{
+		logger:     logger,
+		perBot:     perBot,
+		perAccount: perAccount,
+		accounts:   make(map[string]*rate.Limiter),
+	}
+}
+
+// Wrap queues the commands sent to a bot so they're sent within the bot's and account's limits, commands are sent in
+// the order they're queued until ctx is done
+// Bots with no account only have the per bot limit applied
+func (d *Dispatcher) Wrap(ctx context.Context, client BotClient, account string) QueuedClient {
+	c := &rateLimitedClient{
+		logger: d.logger.With(zap.String("account", account)),
+		client: client,
+		notify: make(chan struct{}, 1),
+	}
+	if limiter := newLimiter(d.perBot); limiter != nil {
+		c.limiters = append(c.limiters, limiter)
+	}
+	if limiter := d.accountLimiter(account); limiter != nil {
+		c.limiters = append(c.limiters, limiter)
+	}
+	go c.run(ctx)
+	return c
+}
+
+// accountLimiter returns the limiter shared between all bots using an account
+func (d *Dispatcher) accountLimiter(account string) *rate.Limiter {
+	if account == "" {
+		return nil
+	}
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	limiter, ok := d.accounts[account]
+	if !ok {
+		limiter = newLimiter(d.perAccount)
+		d.accounts[account] = limiter
+	}
+	return limiter
+}
+
+func newLimiter(limit Limit) *rate.Limiter {
+	if limit == (Limit{}) {
+		return nil
+	}
+	burst := limit.Burst
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(limit.Rate), burst)
+}
+
+// SendJoinChannel queues a Join Channel request to a bot
+func (c *rateLimitedClient) SendJoinChannel(commandID, channel string) error {
+	c.enqueue(command{id: commandID, join: true, channel: channel})
+	return nil
+}
+
+// SendLeaveChannel queues a Leave Channel request to a bot, leaves aren't limited but are queued behind any joins so
+// they're sent in order
+func (c *rateLimitedClient) SendLeaveChannel(commandID, channel string) error {
+	c.enqueue(command{id: commandID, channel: channel})
+	return nil
+}
+
+// SendConfig sends the configuration a bot should use straight away, it isn't limited or queued
+func (c *rateLimitedClient) SendConfig(config Config) error {
+	return c.client.SendConfig(config)
+}
+
+// SendShutdown tells a bot to stop straight away, it isn't limited or queued
+func (c *rateLimitedClient) SendShutdown(reason string) error {
+	return c.client.SendShutdown(reason)
+}
+
+// QueueDepth returns the number of commands waiting to be sent
+func (c *rateLimitedClient) QueueDepth() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return len(c.queue)
+}
+
+// OnSent calls sent with each command's ID once it has been sent to the bot, with the error if it couldn't be
+func (c *rateLimitedClient) OnSent(sent func(commandID string, err error)) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.sent = sent
+}
+
+func (c *rateLimitedClient) enqueue(cmd command) {
+	c.mux.Lock()
+	c.queue = append(c.queue, cmd)
+	c.mux.Unlock()
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run sends queued commands as the limits allow
+func (c *rateLimitedClient) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.notify:
+		}
+		for {
+			c.mux.Lock()
+			if len(c.queue) == 0 {
+				c.mux.Unlock()
+				break
+			}
+			cmd := c.queue[0]
+			c.mux.Unlock()
+
+			err := c.send(ctx, cmd)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				c.logger.Warn("failed to send command", zap.String("channel", cmd.channel), zap.Bool("join", cmd.join), zap.Error(err))
+			}
+			c.mux.Lock()
+			c.queue = c.queue[1:]
+			sent := c.sent
+			c.mux.Unlock()
+			if sent != nil {
+				sent(cmd.id, err)
+			}
+		}
+	}
+}
+
+func (c *rateLimitedClient) send(ctx context.Context, cmd command) error {
+	if !cmd.join {
+		return c.client.SendLeaveChannel(cmd.id, cmd.channel)
+	}
+	for _, limiter := range c.limiters {
+		if err := limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return c.client.SendJoinChannel(cmd.id, cmd.channel)
+}
@@ -0,0 +1,102 @@
+package proto_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ch629/bot-orchestrator/internal/pkg/proto"
+	"github.com/ch629/bot-orchestrator/internal/pkg/proto/mocks"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_DispatcherSendsInOrder(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var sent []string
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		sent = append(sent, args.String(0)+": join "+args.String(1))
+	})
+	mockBotClient.On("SendLeaveChannel", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		sent = append(sent, args.String(0)+": leave "+args.String(1))
+	})
+	dispatcher := proto.NewDispatcher(zap.NewNop(), proto.Limit{Rate: 1000, Burst: 1}, proto.Limit{})
+	client := dispatcher.Wrap(ctx, mockBotClient, "")
+
+	require.NoError(t, client.SendJoinChannel("1", "foo"))
+	require.NoError(t, client.SendJoinChannel("2", "bar"))
+	require.NoError(t, client.SendLeaveChannel("3", "foo"))
+	require.Eventually(t, func() bool {
+		return client.QueueDepth() == 0
+	}, time.Second, time.Millisecond)
+	require.Equal(t, []string{"1: join foo", "2: join bar", "3: leave foo"}, sent)
+}
+
+func Test_DispatcherSharesAccountLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sent := make(chan string, 4)
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		sent <- args.String(1)
+	})
+	// Two JOINs are allowed straight away, then one an hour
+	dispatcher := proto.NewDispatcher(zap.NewNop(), proto.Limit{}, proto.Limit{Rate: 1.0 / 3600, Burst: 2})
+	first := dispatcher.Wrap(ctx, mockBotClient, "modbot")
+	second := dispatcher.Wrap(ctx, mockBotClient, "modbot")
+	other := dispatcher.Wrap(ctx, mockBotClient, "otherbot")
+
+	require.NoError(t, first.SendJoinChannel("1", "a"))
+	require.NoError(t, second.SendJoinChannel("2", "b"))
+	require.NoError(t, second.SendJoinChannel("3", "c"))
+	require.NoError(t, other.SendJoinChannel("4", "d"))
+	var joined []string
+	for i := 0; i < 3; i++ {
+		select {
+		case channel := <-sent:
+			joined = append(joined, channel)
+		case <-time.After(time.Second):
+			require.Fail(t, "timed out waiting for JOINs")
+		}
+	}
+	require.Contains(t, joined, "d", "other accounts shouldn't be limited")
+	// The third JOIN on the shared account should be queued rather than dropped
+	require.Equal(t, 1, first.QueueDepth()+second.QueueDepth())
+	require.Empty(t, sent)
+}
+
+func Test_DispatcherOnSent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errLeave := errors.New("stream closed")
+	mockBotClient := &mocks.BotClient{}
+	mockBotClient.On("SendJoinChannel", "1", "foo").Return(nil)
+	mockBotClient.On("SendLeaveChannel", "2", "foo").Return(errLeave)
+	dispatcher := proto.NewDispatcher(zap.NewNop(), proto.Limit{Rate: 1000, Burst: 1}, proto.Limit{})
+	client := dispatcher.Wrap(ctx, mockBotClient, "")
+	type result struct {
+		commandID string
+		err       error
+	}
+	sent := make(chan result, 2)
+	client.OnSent(func(commandID string, err error) {
+		sent <- result{commandID: commandID, err: err}
+	})
+
+	require.NoError(t, client.SendJoinChannel("1", "foo"))
+	require.NoError(t, client.SendLeaveChannel("2", "foo"))
+	var results []result
+	for i := 0; i < 2; i++ {
+		select {
+		case r := <-sent:
+			results = append(results, r)
+		case <-time.After(time.Second):
+			require.Fail(t, "timed out waiting for commands to be sent")
+		}
+	}
+	require.Equal(t, []result{{commandID: "1"}, {commandID: "2", err: errLeave}}, results)
+}
@@ -2,20 +2,52 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	proto "github.com/ch629/bot-orchestrator/internal/pkg/proto"
+
+	mock "github.com/stretchr/testify/mock"
+)
 
 // BotClient is an autogenerated mock type for the BotClient type
 type BotClient struct {
 	mock.Mock
 }
 
-// SendJoinChannel provides a mock function with given fields: channel
-func (_m *BotClient) SendJoinChannel(channel string) error {
-	ret := _m.Called(channel)
+// SendConfig provides a mock function with given fields: config
+func (_m *BotClient) SendConfig(config proto.Config) error {
+	ret := _m.Called(config)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(channel)
+	if rf, ok := ret.Get(0).(func(proto.Config) error); ok {
+		r0 = rf(config)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SendJoinChannel provides a mock function with given fields: commandID, channel
+func (_m *BotClient) SendJoinChannel(commandID string, channel string) error {
+	ret := _m.Called(commandID, channel)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(commandID, channel)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SendLeaveChannel provides a mock function with given fields: commandID, channel
+func (_m *BotClient) SendLeaveChannel(commandID string, channel string) error {
+	ret := _m.Called(commandID, channel)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, string) error); ok {
+		r0 = rf(commandID, channel)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -23,13 +55,13 @@ func (_m *BotClient) SendJoinChannel(channel string) error {
 	return r0
 }
 
-// SendLeaveChannel provides a mock function with given fields: channel
-func (_m *BotClient) SendLeaveChannel(channel string) error {
-	ret := _m.Called(channel)
+// SendShutdown provides a mock function with given fields: reason
+func (_m *BotClient) SendShutdown(reason string) error {
+	ret := _m.Called(reason)
 
 	var r0 error
 	if rf, ok := ret.Get(0).(func(string) error); ok {
-		r0 = rf(channel)
+		r0 = rf(reason)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -0,0 +1,192 @@
+package scale
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Autoscaler keeps the number of bots at the number recommended for a target
+//
+//go:generate mockery --name Autoscaler --disable-version-string
+type Autoscaler interface {
+	// Recommendation returns how many bots are running and how many should be
+	Recommendation() Recommendation
+	// Run scales the bots towards the recommendation until ctx is done
+	Run(ctx context.Context)
+}
+
+// Option configures optional behaviour of the autoscaler
+type Option func(a *autoscaler)
+
+type autoscaler struct {
+	logger         *zap.Logger
+	service        bots.Service
+	scaler         Scaler
+	target         Target
+	interval       time.Duration
+	startupTimeout time.Duration
+
+	mux sync.Mutex
+	// expected is the number of bots there should be once the bots last started have connected
+	expected  int
+	startedAt time.Time
+	// draining is the bot being drained before it's stopped, uuid.Nil if none
+	draining uuid.UUID
+}
+
+// New creates an Autoscaler which uses scaler to start and stop bots, aiming for the number of bots needed to meet
+// target
+func New(logger *zap.Logger, service bots.Service, scaler Scaler, target Target, opts ...Option) Autoscaler {
+	a := &autoscaler{
+		logger:         logger,
+		service:        service,
+		scaler:         scaler,
+		target:         target,
+		interval:       30 * time.Second,
+		startupTimeout: 2 * time.Minute,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// WithInterval sets how often the number of bots is checked against the recommendation
+func WithInterval(interval time.Duration) Option {
+	return func(a *autoscaler) {
+		a.interval = interval
+	}
+}
+
+// WithStartupTimeout sets how long to wait for started bots to connect before starting more
+func WithStartupTimeout(timeout time.Duration) Option {
+	return func(a *autoscaler) {
+		a.startupTimeout = timeout
+	}
+}
+
+// Recommendation returns how many bots are running and how many should be
+func (a *autoscaler) Recommendation() Recommendation {
+	return Recommend(a.service.BotInfo(), len(a.service.PendingChannels()), a.target)
+}
+
+// Run scales the bots towards the recommendation every interval until ctx is done
+func (a *autoscaler) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		a.reconcile(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcile starts or stops bots to move towards the recommendation, one bot is stopped at a time
+func (a *autoscaler) reconcile(ctx context.Context) {
+	a.mux.Lock()
+	defer a.mux.Unlock()
+	if a.draining != uuid.Nil {
+		a.stopDrained(ctx)
+		return
+	}
+	rec := a.Recommendation()
+	switch {
+	case rec.Desired > rec.Current:
+		a.scaleUp(ctx, rec)
+	case rec.Desired < rec.Current:
+		a.scaleDown(ctx)
+	}
+}
+
+func (a *autoscaler) scaleUp(ctx context.Context, rec Recommendation) {
+	if rec.Current < a.expected && time.Since(a.startedAt) < a.startupTimeout {
+		// Still waiting for the last bots started to connect
+		return
+	}
+	n := rec.Desired - rec.Current
+	a.logger.Info("scaling up", zap.Int("current", rec.Current), zap.Int("desired", rec.Desired))
+	if err := a.scaler.ScaleUp(ctx, n); err != nil {
+		a.logger.Error("failed to scale up", zap.Error(err))
+		return
+	}
+	a.expected = rec.Desired
+	a.startedAt = time.Now()
+}
+
+// scaleDown drains the bot started by the scaler with the fewest channels, which is stopped once drained
+func (a *autoscaler) scaleDown(ctx context.Context) {
+	candidates := make([]bots.BotInfo, 0)
+	for _, bot := range a.service.BotInfo() {
+		if !bot.Draining && a.scaler.Owns(bot) {
+			candidates = append(candidates, bot)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		left, right := len(candidates[i].Channels), len(candidates[j].Channels)
+		if left == right {
+			return candidates[i].ID.String() < candidates[j].ID.String()
+		}
+		return left < right
+	})
+	id := candidates[0].ID
+	a.logger.Info("scaling down", zap.String("bot_id", id.String()))
+	if err := a.service.Drain(id); err != nil {
+		a.logger.Error("failed to drain bot", zap.String("bot_id", id.String()), zap.Error(err))
+		return
+	}
+	a.draining = id
+	a.stopDrained(ctx)
+}
+
+// stopDrained stops the bot being drained once it has no channels left
+func (a *autoscaler) stopDrained(ctx context.Context) {
+	logger := a.logger.With(zap.String("bot_id", a.draining.String()))
+	status, err := a.service.DrainStatus(a.draining)
+	if errors.Is(err, bots.ErrBotNotExist) {
+		// Already gone
+		a.draining = uuid.Nil
+		return
+	} else if err != nil {
+		logger.Error("failed to get drain status", zap.Error(err))
+		return
+	}
+	if !status.Draining {
+		logger.Info("bot was uncordoned, not scaling down")
+		a.draining = uuid.Nil
+		return
+	}
+	if !status.Complete {
+		return
+	}
+	for _, bot := range a.service.BotInfo() {
+		if bot.ID != a.draining {
+			continue
+		}
+		if err := a.scaler.ScaleDown(ctx, bot); err != nil {
+			logger.Error("failed to stop bot, uncordoning it", zap.Error(err))
+			if err := a.service.Uncordon(a.draining); err != nil && !errors.Is(err, bots.ErrBotNotExist) {
+				logger.Warn("failed to uncordon bot", zap.Error(err))
+			}
+			a.draining = uuid.Nil
+			return
+		}
+	}
+	if err := a.service.RemoveBot(a.draining); err != nil && !errors.Is(err, bots.ErrBotNotExist) {
+		logger.Warn("failed to remove bot", zap.Error(err))
+	}
+	logger.Info("bot stopped")
+	a.draining = uuid.Nil
+}
@@ -0,0 +1,124 @@
+package scale
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
+	botsMocks "github.com/ch629/bot-orchestrator/internal/pkg/bots/mocks"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// mockScaler is a Scaler mock, a generated one can't be used from inside the package without an import cycle
+type mockScaler struct {
+	mock.Mock
+}
+
+func (m *mockScaler) ScaleUp(ctx context.Context, n int) error {
+	return m.Called(ctx, n).Error(0)
+}
+
+func (m *mockScaler) ScaleDown(ctx context.Context, bot bots.BotInfo) error {
+	return m.Called(ctx, bot).Error(0)
+}
+
+func (m *mockScaler) Owns(bot bots.BotInfo) bool {
+	return m.Called(bot).Bool(0)
+}
+
+func Test_autoscalerScaleUp(t *testing.T) {
+	mockService, mockScaler := &botsMocks.Service{}, &mockScaler{}
+	mockService.On("BotInfo").Return([]bots.BotInfo{{ID: uuid.New(), Channels: []string{"a", "b", "c"}}})
+	mockService.On("PendingChannels").Return([]bots.PendingChannel{{Channel: "d"}})
+	mockScaler.On("ScaleUp", mock.Anything, 1).Return(nil).Once()
+	a := New(zap.NewNop(), mockService, mockScaler, Target{ChannelsPerBot: 2}).(*autoscaler)
+
+	a.reconcile(context.Background())
+	// The started bot hasn't connected yet, so no more should be started
+	a.reconcile(context.Background())
+	mockScaler.AssertExpectations(t)
+}
+
+func Test_autoscalerScaleDown(t *testing.T) {
+	busy, quiet := uuid.New(), uuid.New()
+	mockService, mockScaler := &botsMocks.Service{}, &mockScaler{}
+	quietBot := bots.BotInfo{ID: quiet, Channels: []string{"c"}}
+	mockService.On("BotInfo").Return([]bots.BotInfo{
+		{ID: busy, Channels: []string{"a", "b"}},
+		quietBot,
+	}).Once()
+	mockService.On("PendingChannels").Return([]bots.PendingChannel{})
+	mockScaler.On("Owns", mock.Anything).Return(true)
+	a := New(zap.NewNop(), mockService, mockScaler, Target{ChannelsPerBot: 10}).(*autoscaler)
+
+	// The quietest bot is drained first
+	mockService.On("BotInfo").Return([]bots.BotInfo{{ID: busy, Channels: []string{"a", "b"}}, quietBot}).Once()
+	mockService.On("Drain", quiet).Return(nil)
+	mockService.On("DrainStatus", quiet).Return(bots.DrainStatus{ID: quiet, Draining: true, Remaining: 1}, nil).Twice()
+	a.reconcile(context.Background())
+	a.reconcile(context.Background())
+	mockScaler.AssertNotCalled(t, "ScaleDown", mock.Anything, mock.Anything)
+
+	// Only stopped once drained
+	drained := bots.BotInfo{ID: quiet, Channels: []string{}, Draining: true}
+	mockService.On("DrainStatus", quiet).Return(bots.DrainStatus{ID: quiet, Draining: true, Complete: true}, nil).Once()
+	mockService.On("BotInfo").Return([]bots.BotInfo{{ID: busy, Channels: []string{"a", "b", "c"}}, drained}).Once()
+	mockScaler.On("ScaleDown", mock.Anything, drained).Return(nil)
+	mockService.On("RemoveBot", quiet).Return(nil)
+	a.reconcile(context.Background())
+	require.Equal(t, uuid.Nil, a.draining)
+	mockService.AssertExpectations(t)
+	mockScaler.AssertExpectations(t)
+}
+
+func Test_autoscalerUncordoned(t *testing.T) {
+	id := uuid.New()
+	mockService, mockScaler := &botsMocks.Service{}, &mockScaler{}
+	mockService.On("DrainStatus", id).Return(bots.DrainStatus{ID: id}, nil)
+	a := New(zap.NewNop(), mockService, mockScaler, Target{}).(*autoscaler)
+	a.draining = id
+
+	a.reconcile(context.Background())
+	require.Equal(t, uuid.Nil, a.draining, "the bot shouldn't be stopped once uncordoned")
+	mockScaler.AssertNotCalled(t, "ScaleDown", mock.Anything, mock.Anything)
+}
+
+func Test_autoscalerScaleDownOwnedBots(t *testing.T) {
+	owned, unowned := uuid.New(), uuid.New()
+	mockService, mockScaler := &botsMocks.Service{}, &mockScaler{}
+	ownedBot := bots.BotInfo{ID: owned, Channels: []string{"a", "b"}, Labels: map[string]string{"instance": "1"}}
+	unownedBot := bots.BotInfo{ID: unowned, Channels: []string{"c"}}
+	mockService.On("BotInfo").Return([]bots.BotInfo{ownedBot, unownedBot})
+	mockService.On("PendingChannels").Return([]bots.PendingChannel{})
+	mockScaler.On("Owns", ownedBot).Return(true)
+	mockScaler.On("Owns", unownedBot).Return(false)
+	mockService.On("Drain", owned).Return(nil)
+	mockService.On("DrainStatus", owned).Return(bots.DrainStatus{ID: owned, Draining: true, Remaining: 2}, nil)
+	a := New(zap.NewNop(), mockService, mockScaler, Target{ChannelsPerBot: 10}).(*autoscaler)
+
+	// The quieter bot wasn't started by the scaler, so it can't be stopped
+	a.reconcile(context.Background())
+	require.Equal(t, owned, a.draining)
+	mockService.AssertNotCalled(t, "Drain", unowned)
+}
+
+func Test_autoscalerScaleDownFailed(t *testing.T) {
+	id := uuid.New()
+	drained := bots.BotInfo{ID: id, Channels: []string{}, Draining: true}
+	mockService, mockScaler := &botsMocks.Service{}, &mockScaler{}
+	mockService.On("DrainStatus", id).Return(bots.DrainStatus{ID: id, Draining: true, Complete: true}, nil)
+	mockService.On("BotInfo").Return([]bots.BotInfo{drained})
+	mockScaler.On("ScaleDown", mock.Anything, drained).Return(ErrUnknownBot)
+	mockService.On("Uncordon", id).Return(nil)
+	a := New(zap.NewNop(), mockService, mockScaler, Target{}).(*autoscaler)
+	a.draining = id
+
+	// The bot is put back into service rather than retrying forever
+	a.reconcile(context.Background())
+	require.Equal(t, uuid.Nil, a.draining)
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "RemoveBot", id)
+}
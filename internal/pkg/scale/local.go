@@ -0,0 +1,84 @@
+package scale
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ErrUnknownBot is returned when stopping a bot which wasn't started by the scaler
+var ErrUnknownBot = errors.New("bot wasn't started by this scaler")
+
+type localScaler struct {
+	logger *zap.Logger
+	path   string
+	args   []string
+	mux    sync.Mutex
+	// processes are the running bots, by the instance label they were started with
+	processes map[string]*exec.Cmd
+}
+
+// NewLocalScaler creates a Scaler which runs each bot as a local process of the binary at path, e.g. a build of
+// cmd/client, with the given arguments
+// Each process is also given -labels instance=<id> so its bot can be found again when stopping it, processes still
+// running are killed when the ctx they were started with is done
+func NewLocalScaler(logger *zap.Logger, path string, args ...string) Scaler {
+	return &localScaler{
+		logger:    logger,
+		path:      path,
+		args:      args,
+		processes: make(map[string]*exec.Cmd),
+	}
+}
+
+// ScaleUp starts n more bot processes
+func (l *localScaler) ScaleUp(ctx context.Context, n int) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	for i := 0; i < n; i++ {
+		instance := uuid.NewString()
+		args := append(append([]string{}, l.args...), "-labels", "instance="+instance)
+		cmd := exec.CommandContext(ctx, l.path, args...)
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+		logger := l.logger.With(zap.String("instance", instance), zap.Int("pid", cmd.Process.Pid))
+		logger.Info("started bot process")
+		l.processes[instance] = cmd
+		go func() {
+			err := cmd.Wait()
+			logger.Info("bot process exited", zap.Error(err))
+			l.mux.Lock()
+			defer l.mux.Unlock()
+			delete(l.processes, instance)
+		}()
+	}
+	return nil
+}
+
+// Owns returns whether the bot's process was started by the scaler and is still running
+func (l *localScaler) Owns(bot bots.BotInfo) bool {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	_, ok := l.processes[bot.Labels["instance"]]
+	return ok
+}
+
+// ScaleDown interrupts the process running a bot
+// Returns ErrUnknownBot if the bot's process wasn't started by the scaler
+func (l *localScaler) ScaleDown(_ context.Context, bot bots.BotInfo) error {
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	cmd, ok := l.processes[bot.Labels["instance"]]
+	if !ok {
+		return ErrUnknownBot
+	}
+	return cmd.Process.Signal(os.Interrupt)
+}
@@ -0,0 +1,60 @@
+package scale
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"testing"
+	"time"
+
+	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// TestHelperProcess stands in for a bot process started by the local scaler, it runs until interrupted
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("SCALE_HELPER_PROCESS") != "1" {
+		return
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	<-ctx.Done()
+	os.Exit(0)
+}
+
+func Test_localScaler(t *testing.T) {
+	t.Setenv("SCALE_HELPER_PROCESS", "1")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	scaler := NewLocalScaler(zap.NewNop(), os.Args[0], "-test.run=TestHelperProcess", "--").(*localScaler)
+	require.NoError(t, scaler.ScaleUp(ctx, 2))
+
+	scaler.mux.Lock()
+	instances := make([]string, 0, len(scaler.processes))
+	for instance := range scaler.processes {
+		instances = append(instances, instance)
+	}
+	scaler.mux.Unlock()
+	require.Len(t, instances, 2)
+	instance := instances[0]
+
+	require.True(t, scaler.Owns(bots.BotInfo{Labels: map[string]string{"instance": instance}}))
+	require.False(t, scaler.Owns(bots.BotInfo{}))
+	require.NoError(t, scaler.ScaleDown(ctx, bots.BotInfo{Labels: map[string]string{"instance": instance}}))
+	require.Eventually(t, func() bool {
+		scaler.mux.Lock()
+		defer scaler.mux.Unlock()
+		_, running := scaler.processes[instance]
+		return !running && len(scaler.processes) == 1
+	}, 3*time.Second, 10*time.Millisecond)
+	require.ErrorIs(t, scaler.ScaleDown(ctx, bots.BotInfo{}), ErrUnknownBot)
+
+	// Wait for the other process to be killed, it shares the test's output so the test can't finish while it's running
+	cancel()
+	require.Eventually(t, func() bool {
+		scaler.mux.Lock()
+		defer scaler.mux.Unlock()
+		return len(scaler.processes) == 0
+	}, 3*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,80 @@
+package scale
+
+import (
+	"context"
+	"math"
+
+	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
+)
+
+type (
+	// Target is the capacity and load each bot should run at, used to work out how many bots are needed
+	Target struct {
+		// ChannelsPerBot is the number of channels each bot should run, 0 ignores the number of channels
+		ChannelsPerBot int
+		// LoadPerBot is the messages per second each bot should handle, 0 ignores load
+		LoadPerBot float64
+		// Min and Max bound the number of bots, a Max of 0 means no limit
+		Min int
+		Max int
+	}
+
+	// Recommendation is a struct containing how many bots are running and how many should be
+	Recommendation struct {
+		Current int `json:"current"`
+		Desired int `json:"desired"`
+		// Channels is the number of channels running or waiting to be placed
+		Channels int `json:"channels"`
+		// Load is the total messages per second across every bot
+		Load float64 `json:"load"`
+	}
+)
+
+// Scaler starts and stops bots
+type Scaler interface {
+	// ScaleUp starts n more bots, which connect to the orchestrator themselves
+	ScaleUp(ctx context.Context, n int) error
+	// ScaleDown stops a bot, which has already been drained
+	ScaleDown(ctx context.Context, bot bots.BotInfo) error
+	// Owns returns whether the bot was started by the scaler, only these bots can be stopped
+	Owns(bot bots.BotInfo) bool
+}
+
+// Recommend works out how many bots are needed to run the channels within the target, bots being drained aren't
+// counted as running
+func Recommend(botInfos []bots.BotInfo, pending int, target Target) Recommendation {
+	rec := Recommendation{
+		Channels: pending,
+	}
+	for _, bot := range botInfos {
+		if !bot.Draining {
+			rec.Current++
+		}
+		rec.Channels += len(bot.Channels)
+		rec.Load += bot.Load
+	}
+	rec.Desired = rec.Current
+	if target.ChannelsPerBot > 0 || target.LoadPerBot > 0 {
+		rec.Desired = 0
+	}
+	if target.ChannelsPerBot > 0 {
+		rec.Desired = max(rec.Desired, int(math.Ceil(float64(rec.Channels)/float64(target.ChannelsPerBot))))
+	}
+	if target.LoadPerBot > 0 {
+		rec.Desired = max(rec.Desired, int(math.Ceil(rec.Load/target.LoadPerBot)))
+	}
+	if rec.Desired < target.Min {
+		rec.Desired = target.Min
+	}
+	if target.Max > 0 && rec.Desired > target.Max {
+		rec.Desired = target.Max
+	}
+	return rec
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
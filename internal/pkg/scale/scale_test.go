@@ -0,0 +1,75 @@
+package scale_test
+
+import (
+	"testing"
+
+	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
+	"github.com/ch629/bot-orchestrator/internal/pkg/scale"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Recommend(t *testing.T) {
+	twoBots := []bots.BotInfo{
+		{ID: uuid.New(), Channels: []string{"a", "b", "c"}, Load: 30},
+		{ID: uuid.New(), Channels: []string{"d"}, Load: 10},
+	}
+	tests := []struct {
+		name     string
+		bots     []bots.BotInfo
+		pending  int
+		target   scale.Target
+		expected scale.Recommendation
+	}{
+		{
+			name:     "No target keeps the current bots",
+			bots:     twoBots,
+			expected: scale.Recommendation{Current: 2, Desired: 2, Channels: 4, Load: 40},
+		},
+		{
+			name:     "Channels per bot",
+			bots:     twoBots,
+			pending:  1,
+			target:   scale.Target{ChannelsPerBot: 2},
+			expected: scale.Recommendation{Current: 2, Desired: 3, Channels: 5, Load: 40},
+		},
+		{
+			name:     "Load per bot",
+			bots:     twoBots,
+			target:   scale.Target{ChannelsPerBot: 10, LoadPerBot: 10},
+			expected: scale.Recommendation{Current: 2, Desired: 4, Channels: 4, Load: 40},
+		},
+		{
+			name:     "Scale down",
+			bots:     twoBots,
+			target:   scale.Target{ChannelsPerBot: 10},
+			expected: scale.Recommendation{Current: 2, Desired: 1, Channels: 4, Load: 40},
+		},
+		{
+			name:     "Bounded by max",
+			bots:     twoBots,
+			target:   scale.Target{ChannelsPerBot: 1, Max: 3},
+			expected: scale.Recommendation{Current: 2, Desired: 3, Channels: 4, Load: 40},
+		},
+		{
+			name:     "Bounded by min",
+			target:   scale.Target{ChannelsPerBot: 1, Min: 1},
+			expected: scale.Recommendation{Desired: 1},
+		},
+		{
+			name: "Draining bots aren't counted",
+			bots: []bots.BotInfo{
+				{ID: uuid.New(), Channels: []string{"a"}},
+				{ID: uuid.New(), Channels: []string{"b"}, Draining: true},
+			},
+			target:   scale.Target{ChannelsPerBot: 2},
+			expected: scale.Recommendation{Current: 1, Desired: 1, Channels: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, scale.Recommend(tt.bots, tt.pending, tt.target))
+		})
+	}
+}
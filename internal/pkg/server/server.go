@@ -2,8 +2,13 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
 	proto2 "github.com/ch629/bot-orchestrator/internal/pkg/proto"
@@ -11,22 +16,97 @@ import (
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
-func New(logger *zap.Logger, botsService bots.Service) *server {
-	return &server{
-		logger:      logger,
-		botsService: botsService,
+const (
+	// defaultMetricsInterval is how often bots connected with Connect report their message rates by default
+	defaultMetricsInterval = 10 * time.Second
+	// defaultHeartbeatInterval is how often bots connected with Connect send heartbeats by default
+	defaultHeartbeatInterval = 10 * time.Second
+	// defaultKeepaliveTime is how long a connection can be idle before the server pings it by default
+	defaultKeepaliveTime = 30 * time.Second
+	// defaultKeepaliveTimeout is how long the server waits for a ping to be answered before closing the connection
+	defaultKeepaliveTimeout = 10 * time.Second
+	// minPingInterval is the most often clients can ping the server before it closes the connection
+	minPingInterval = 15 * time.Second
+	// maxHostnameLength is the longest hostname a bot can register with
+	maxHostnameLength = 253
+)
+
+const (
+	// protocolJoinStream is the protocol version JoinStream was introduced with, bots can only be sent commands
+	protocolJoinStream = 1
+	// protocolConnect is the protocol version Connect was introduced with, bots acknowledge commands by ID and send
+	// heartbeats
+	protocolConnect = 2
+	// maxProtocolVersion is the newest protocol version the orchestrator speaks
+	maxProtocolVersion = protocolConnect
+)
+
+// Option configures the gRPC server
+type Option func(s *server)
+
+// WithMetricsInterval sets how often bots connected with Connect are told to report their message rates
+func WithMetricsInterval(interval time.Duration) Option {
+	return func(s *server) {
+		s.metricsInterval = interval
+	}
+}
+
+// WithHeartbeatInterval sets how often bots connected with Connect are told to send heartbeats
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(s *server) {
+		s.heartbeatInterval = interval
+	}
+}
+
+// WithKeepalive sets how long a connection can be idle before the server pings it, and how long the server waits for
+// the ping to be answered before closing the connection, so bots behind a dead connection are noticed
+func WithKeepalive(idle, timeout time.Duration) Option {
+	return func(s *server) {
+		s.keepalive = keepalive.ServerParameters{
+			Time:    idle,
+			Timeout: timeout,
+		}
 	}
 }
 
+// New creates a gRPC server for bots to connect to, commands sent to bots are rate limited by dispatcher unless it's nil
+func New(logger *zap.Logger, botsService bots.Service, dispatcher *proto2.Dispatcher, opts ...Option) *server {
+	s := &server{
+		logger:            logger,
+		botsService:       botsService,
+		dispatcher:        dispatcher,
+		metricsInterval:   defaultMetricsInterval,
+		heartbeatInterval: defaultHeartbeatInterval,
+		keepalive: keepalive.ServerParameters{
+			Time:    defaultKeepaliveTime,
+			Timeout: defaultKeepaliveTimeout,
+		},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
 func (s *server) Start(ctx context.Context, port int) error {
 	lis, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
 	if err != nil {
 		return err
 	}
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(
+		grpc.KeepaliveParams(s.keepalive),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             minPingInterval,
+			PermitWithoutStream: true,
+		}),
+	)
 
 	go func() {
 		<-ctx.Done()
@@ -41,26 +121,407 @@ func (s *server) Start(ctx context.Context, port int) error {
 type server struct {
 	botsService bots.Service
 	logger      *zap.Logger
+	dispatcher  *proto2.Dispatcher
+	// metricsInterval is how often bots connected with Connect report their message rates
+	metricsInterval time.Duration
+	// heartbeatInterval is how often bots connected with Connect send heartbeats
+	heartbeatInterval time.Duration
+	keepalive         keepalive.ServerParameters
 
 	proto.UnimplementedOrchestratorServer
 }
 
-// TODO: Should this be bidirectional, so the bots can send metrics back to us?
-func (s *server) JoinStream(_ *proto.EmptyMessage, resp proto.Orchestrator_JoinStreamServer) error {
-	// TODO: Should this ID be passed in the request instead? -> or could generate it inside of the botsService, but then have another func to notify ready?
-	id := uuid.New()
-	if err := resp.SendHeader(metadata.Pairs("bot_id", id.String())); err != nil {
+// JoinStream joins a bot which can only receive commands, kept for bots which don't support Connect yet
+func (s *server) JoinStream(req *proto.Hello, resp proto.Orchestrator_JoinStreamServer) error {
+	hello, err := metadataHello(resp.Context())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid bot metadata: %v", err)
+	}
+	// Older bots only describe themselves in the metadata, anything set in the request takes precedence
+	protobuf.Merge(hello, req)
+	registered := hello.ProtocolVersion != 0
+	if err := checkProtocol(hello, protocolJoinStream); err != nil {
+		return err
+	}
+	opts, err := botOptions(hello, registered)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid bot metadata: %v", err)
+	}
+	// JoinStream bots can only confirm joins
+	opts = append(opts, bots.WithUnacknowledgedCommands())
+	// JoinStream bots aren't given a session token so they can't resume, their channels move as soon as they disconnect
+	sess := session{id: uuid.New()}
+	// The header is only sent once the bot has joined, so the bot can be found by its ID as soon as it's received
+	if err := resp.SetHeader(sess.header()); err != nil {
 		return fmt.Errorf("failed to set bot_id header: %w", err)
 	}
 	// TODO: Return a chan instead of context
-	ctx := s.botsService.Join(resp.Context(), id, proto2.NewClient(resp))
+	var botClient proto2.BotClient = proto2.NewClient(resp)
+	if s.dispatcher != nil {
+		botClient = s.dispatcher.Wrap(resp.Context(), botClient, hello.Account)
+	}
+	ctx, err := s.join(resp.Context(), sess, botClient, opts)
+	if err != nil {
+		return err
+	}
+	// Fails if a command sent while joining has already sent the header
+	_ = resp.SendHeader(nil)
 
-	defer func() {
-		if err := s.botsService.Leave(id); err != nil {
-			s.logger.Warn("failed to leave", zap.String("bot_id", id.String()), zap.Error(err))
-		}
-	}()
+	defer s.leave(sess.id)
 
 	<-ctx.Done()
 	return nil
 }
+
+// Connect joins a bot over a bidirectional stream, the bot describes itself in a Hello then acknowledges joins and
+// reports its metrics and errors while the orchestrator sends it commands
+func (s *server) Connect(stream proto.Orchestrator_ConnectServer) error {
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	hello := msg.GetHello()
+	if hello == nil {
+		return status.Error(codes.InvalidArgument, "first message must be a Hello")
+	}
+	registered := hello.ProtocolVersion != 0
+	if err := checkProtocol(hello, protocolConnect); err != nil {
+		return err
+	}
+	opts, err := botOptions(hello, registered)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid hello: %v", err)
+	}
+	sess, err := s.newSession(hello)
+	if err != nil {
+		return err
+	}
+	id := sess.id
+	// The header is only sent once the bot has joined, so the bot can be found by its ID as soon as it's received
+	if err := stream.SetHeader(sess.header()); err != nil {
+		return fmt.Errorf("failed to set bot_id header: %w", err)
+	}
+	var botClient proto2.BotClient = proto2.NewStreamClient(stream)
+	if s.dispatcher != nil {
+		botClient = s.dispatcher.Wrap(stream.Context(), botClient, hello.Account)
+	}
+	ctx, err := s.join(stream.Context(), sess, botClient, append(opts, bots.WithHeartbeats()))
+	if err != nil {
+		return err
+	}
+
+	defer s.leave(id)
+
+	// Sends the header too, unless a command sent while joining already has
+	if err := botClient.SendConfig(proto2.Config{
+		MetricsInterval:   s.metricsInterval,
+		HeartbeatInterval: s.heartbeatInterval,
+	}); err != nil {
+		return fmt.Errorf("failed to send config: %w", err)
+	}
+
+	received := make(chan error, 1)
+	go func() {
+		received <- s.receive(id, stream)
+	}()
+	select {
+	case err := <-received:
+		return err
+	case <-ctx.Done():
+		if stream.Context().Err() == nil {
+			// The bot was removed or evicted rather than disconnecting
+			if err := botClient.SendShutdown("removed by orchestrator"); err != nil {
+				s.logger.Warn("failed to send shutdown", zap.String("bot_id", id.String()), zap.Error(err))
+			}
+		}
+		return nil
+	}
+}
+
+// session is the identity given to a connecting bot
+type session struct {
+	id    uuid.UUID
+	token string
+	// resume is set if the bot is resuming its previous session
+	resume bool
+}
+
+// newSession picks the ID and session token for a connecting bot, resuming the session it presented if it can still be
+// resumed and otherwise joining it as a new bot
+func (s *server) newSession(hello *proto.Hello) (session, error) {
+	if hello.BotId == "" {
+		return session{id: uuid.New(), token: uuid.NewString()}, nil
+	}
+	id, err := uuid.Parse(hello.BotId)
+	if err != nil {
+		return session{}, status.Errorf(codes.InvalidArgument, "invalid bot_id: %v", err)
+	}
+	err = s.botsService.CanResume(id, hello.SessionToken)
+	if errors.Is(err, bots.ErrSessionNotExist) {
+		s.logger.Info("session can't be resumed, joining as a new bot", zap.String("bot_id", id.String()))
+		return session{id: uuid.New(), token: uuid.NewString()}, nil
+	}
+	if err != nil {
+		return session{}, resumeStatus(err)
+	}
+	return session{id: id, token: hello.SessionToken, resume: true}, nil
+}
+
+// header is the metadata telling a bot its ID and session token, if it has one
+func (sess session) header() metadata.MD {
+	md := metadata.Pairs("bot_id", sess.id.String())
+	if sess.token != "" {
+		md.Set("session_token", sess.token)
+	}
+	return md
+}
+
+// join joins a bot to the orchestrator, or resumes its previous session
+func (s *server) join(ctx context.Context, sess session, botClient proto2.BotClient, opts []bots.BotOption) (context.Context, error) {
+	if !sess.resume {
+		if sess.token != "" {
+			opts = append(opts, bots.WithSessionToken(sess.token))
+		}
+		return s.botsService.Join(ctx, sess.id, botClient, opts...), nil
+	}
+	botCtx, err := s.botsService.Resume(ctx, sess.id, sess.token, botClient, opts...)
+	if err != nil {
+		return nil, resumeStatus(err)
+	}
+	return botCtx, nil
+}
+
+// resumeStatus converts an error resuming a session to a gRPC status
+func resumeStatus(err error) error {
+	switch {
+	case errors.Is(err, bots.ErrSessionNotExist):
+		// The grace period expired while connecting, the bot can connect again as a new bot
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, bots.ErrInvalidSessionToken):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, bots.ErrSessionInUse):
+		// The old connection hasn't been noticed as dead yet
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return status.Errorf(codes.Internal, "failed to resume session: %v", err)
+	}
+}
+
+// receive handles the messages a bot sends over Connect until the stream is closed
+func (s *server) receive(id uuid.UUID, stream proto.Orchestrator_ConnectServer) error {
+	logger := s.logger.With(zap.String("bot_id", id.String()))
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch payload := msg.Payload.(type) {
+		case *proto.BotMessage_Hello:
+			logger.Warn("ignoring repeated hello")
+		case *proto.BotMessage_Ack:
+			if err := s.ack(id, payload.Ack); err != nil {
+				logger.Warn("failed to acknowledge command",
+					zap.String("channel", payload.Ack.Channel),
+					zap.String("command_id", payload.Ack.CommandId),
+					zap.Error(err),
+				)
+			}
+		case *proto.BotMessage_Heartbeat:
+			if err := s.botsService.Heartbeat(id); err != nil {
+				logger.Warn("failed to record heartbeat", zap.Error(err))
+			}
+		case *proto.BotMessage_Metrics:
+			if err := s.botsService.ReportLoad(id, payload.Metrics.ChannelRates); err != nil {
+				logger.Warn("failed to report load", zap.Error(err))
+			}
+		case *proto.BotMessage_Error:
+			logger.Warn("bot reported error", zap.String("channel", payload.Error.Channel), zap.String("error", payload.Error.Message))
+		}
+	}
+}
+
+// ack records whether a bot managed to carry out a command, acks without a command ID confirm a join of the channel
+func (s *server) ack(id uuid.UUID, ack *proto.Ack) error {
+	if ack.CommandId == "" {
+		return s.botsService.ConfirmJoin(id, ack.Channel)
+	}
+	return s.botsService.AckCommand(id, ack.CommandId, ackError(ack))
+}
+
+// ackError converts the failure a bot reported in an ack into an error, nil if the command succeeded
+func ackError(ack *proto.Ack) error {
+	var failure error
+	switch ack.Failure {
+	case proto.Ack_BANNED:
+		failure = bots.ErrBanned
+	case proto.Ack_CHANNEL_NOT_FOUND:
+		failure = bots.ErrChannelNotFound
+	case proto.Ack_CHANNEL_SUSPENDED:
+		failure = bots.ErrChannelSuspended
+	}
+	switch {
+	case failure == nil && ack.Error == "":
+		return nil
+	case failure == nil:
+		return errors.New(ack.Error)
+	case ack.Error == "":
+		return failure
+	default:
+		return fmt.Errorf("%w: %s", failure, ack.Error)
+	}
+}
+
+// leave removes a disconnected bot from the orchestrator, unless it has already been evicted
+func (s *server) leave(id uuid.UUID) {
+	if err := s.botsService.Leave(id); err != nil && !errors.Is(err, bots.ErrBotNotExist) {
+		s.logger.Warn("failed to leave", zap.String("bot_id", id.String()), zap.Error(err))
+	}
+}
+
+// metadataHello builds the Hello a bot joining with JoinStream would have sent from the metadata it sent instead
+func metadataHello(ctx context.Context) (*proto.Hello, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	hello := &proto.Hello{}
+	if values := md.Get("max_channels"); len(values) > 0 {
+		maxChannels, err := strconv.Atoi(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("max_channels must be an integer: %q", values[0])
+		}
+		hello.MaxChannels = int32(maxChannels)
+	}
+	if values := md.Get("account"); len(values) > 0 {
+		hello.Account = values[0]
+	}
+	if values := md.Get("version"); len(values) > 0 {
+		hello.Version = values[0]
+	}
+	if values := md.Get("hostname"); len(values) > 0 {
+		hello.Hostname = values[0]
+	}
+	if values := md.Get("platform"); len(values) > 0 {
+		hello.Platform = values[0]
+	}
+	if values := md.Get("protocol_version"); len(values) > 0 {
+		protocolVersion, err := strconv.Atoi(values[0])
+		if err != nil {
+			return nil, fmt.Errorf("protocol_version must be an integer: %q", values[0])
+		}
+		hello.ProtocolVersion = int32(protocolVersion)
+	}
+	if values := md.Get("bot_id"); len(values) > 0 {
+		hello.BotId = values[0]
+	}
+	if values := md.Get("session_token"); len(values) > 0 {
+		hello.SessionToken = values[0]
+	}
+	for _, value := range md.Get("labels") {
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("labels must be key=value: %q", value)
+		}
+		if hello.Labels == nil {
+			hello.Labels = make(map[string]string)
+		}
+		hello.Labels[parts[0]] = parts[1]
+	}
+	return hello, nil
+}
+
+// checkProtocol checks the orchestrator speaks the protocol version a bot connecting over an RPC introduced in
+// version introduced does, bots which don't send a version are assumed to speak introduced
+// Returns a FailedPrecondition status if the bot is incompatible
+func checkProtocol(hello *proto.Hello, introduced int32) error {
+	if hello.ProtocolVersion == 0 {
+		hello.ProtocolVersion = introduced
+	}
+	if hello.ProtocolVersion < introduced || hello.ProtocolVersion > maxProtocolVersion {
+		return status.Errorf(codes.FailedPrecondition, "protocol version %d is not supported, expected %d to %d",
+			hello.ProtocolVersion, introduced, maxProtocolVersion)
+	}
+	return nil
+}
+
+// botOptions builds the options for a joining bot from the Hello it sent, registered bots sent a protocol version and
+// must also send their hostname and platform, older bots don't have to
+func botOptions(hello *proto.Hello, registered bool) ([]bots.BotOption, error) {
+	if hello.MaxChannels < 0 {
+		return nil, fmt.Errorf("max_channels must be a non-negative integer: %d", hello.MaxChannels)
+	}
+	if (registered || hello.Hostname != "") && !validHostname(hello.Hostname) {
+		return nil, fmt.Errorf("hostname must be 1 to %d letters, digits, '-', '_' or '.': %q", maxHostnameLength, hello.Hostname)
+	}
+	if parts := strings.Split(hello.Platform, "/"); (registered || hello.Platform != "") && (len(parts) != 2 || parts[0] == "" || parts[1] == "") {
+		return nil, fmt.Errorf("platform must be os/arch: %q", hello.Platform)
+	}
+	opts := []bots.BotOption{
+		bots.WithMaxChannels(int(hello.MaxChannels)),
+		bots.WithHostname(hello.Hostname),
+		bots.WithPlatform(hello.Platform),
+		bots.WithProtocolVersion(int(hello.ProtocolVersion)),
+	}
+	if hello.Version != "" {
+		opts = append(opts, bots.WithVersion(hello.Version))
+	}
+	labels := make(map[string]string, len(hello.Labels)+1)
+	for k, v := range hello.Labels {
+		if k == "" {
+			return nil, fmt.Errorf("labels must have a key: %q", v)
+		}
+		labels[k] = v
+	}
+	if _, ok := labels["account"]; !ok && hello.Account != "" {
+		// The account is always available to select on
+		labels["account"] = hello.Account
+	}
+	if len(labels) > 0 {
+		opts = append(opts, bots.WithLabels(labels))
+	}
+	return opts, nil
+}
+
+// validHostname returns whether a hostname is non-empty, no longer than maxHostnameLength and only has letters, digits,
+// '-', '_' or '.'
+func validHostname(hostname string) bool {
+	if hostname == "" || len(hostname) > maxHostnameLength {
+		return false
+	}
+	for _, r := range hostname {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// ReportMetrics records the message rates a bot is seeing on each of its channels
+func (s *server) ReportMetrics(_ context.Context, req *proto.MetricsReport) (*proto.EmptyMessage, error) {
+	id, err := uuid.Parse(req.BotId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid bot_id: %v", err)
+	}
+	if err := s.botsService.ReportLoad(id, req.ChannelRates); err != nil {
+		if errors.Is(err, bots.ErrBotNotExist) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to report load: %v", err)
+	}
+	return &proto.EmptyMessage{}, nil
+}
+
+// ConfirmJoin records that a bot has successfully joined a channel
+func (s *server) ConfirmJoin(_ context.Context, req *proto.JoinConfirmation) (*proto.EmptyMessage, error) {
+	id, err := uuid.Parse(req.BotId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid bot_id: %v", err)
+	}
+	if err := s.botsService.ConfirmJoin(id, req.Channel); err != nil {
+		if errors.Is(err, bots.ErrBotNotExist) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to confirm join: %v", err)
+	}
+	return &proto.EmptyMessage{}, nil
+}
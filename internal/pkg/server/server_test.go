@@ -0,0 +1,407 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ch629/bot-orchestrator/internal/pkg/bots"
+	"github.com/ch629/bot-orchestrator/pkg/proto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// dial starts a server for the bots service on an in-memory listener, returning a client connected to it
+func dial(t *testing.T, service bots.Service) proto.OrchestratorClient {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	proto.RegisterOrchestratorServer(grpcServer, New(zap.NewNop(), service, nil))
+	go func() {
+		_ = grpcServer.Serve(lis)
+	}()
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+		grpcServer.Stop()
+	})
+	return proto.NewOrchestratorClient(conn)
+}
+
+// connect joins a bot over Connect with the given Hello, returning the stream once the bot has been sent its config
+func connect(t *testing.T, client proto.OrchestratorClient, hello *proto.Hello) proto.Orchestrator_ConnectClient {
+	t.Helper()
+	stream, err := client.Connect(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&proto.BotMessage{Payload: &proto.BotMessage_Hello{Hello: hello}}))
+	msg, err := stream.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, msg.GetConfig())
+	return stream
+}
+
+func newHello() *proto.Hello {
+	return &proto.Hello{
+		Hostname:        "bot-1",
+		Platform:        "linux/amd64",
+		ProtocolVersion: protocolConnect,
+	}
+}
+
+func Test_serverConnectRegistration(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(hello *proto.Hello)
+		code   codes.Code
+	}{
+		{
+			name:   "Valid",
+			modify: func(*proto.Hello) {},
+			code:   codes.OK,
+		},
+		{
+			name:   "Newer protocol version",
+			modify: func(hello *proto.Hello) { hello.ProtocolVersion = maxProtocolVersion + 1 },
+			code:   codes.FailedPrecondition,
+		},
+		{
+			name:   "Protocol version without Connect",
+			modify: func(hello *proto.Hello) { hello.ProtocolVersion = protocolJoinStream },
+			code:   codes.FailedPrecondition,
+		},
+		{
+			name:   "Negative max_channels",
+			modify: func(hello *proto.Hello) { hello.MaxChannels = -1 },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "Empty hostname",
+			modify: func(hello *proto.Hello) { hello.Hostname = "" },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "Invalid hostname",
+			modify: func(hello *proto.Hello) { hello.Hostname = "bot 1" },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "Hostname too long",
+			modify: func(hello *proto.Hello) { hello.Hostname = strings.Repeat("a", maxHostnameLength+1) },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "Empty platform",
+			modify: func(hello *proto.Hello) { hello.Platform = "" },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "Platform without arch",
+			modify: func(hello *proto.Hello) { hello.Platform = "linux/" },
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:   "Invalid platform",
+			modify: func(hello *proto.Hello) { hello.Platform = "linux" },
+			code:   codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dial(t, bots.New(zap.NewNop()))
+			hello := newHello()
+			tt.modify(hello)
+			stream, err := client.Connect(context.Background())
+			require.NoError(t, err)
+			require.NoError(t, stream.Send(&proto.BotMessage{Payload: &proto.BotMessage_Hello{Hello: hello}}))
+			_, err = stream.Recv()
+			require.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func Test_serverJoinStreamMetadata(t *testing.T) {
+	service := bots.New(zap.NewNop())
+	client := dial(t, service)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx,
+		"max_channels", "5",
+		"account", "modbot",
+		"version", "1.0.0",
+		"hostname", "bot-1",
+		"platform", "linux/amd64",
+		"labels", "zone=eu",
+	)
+	// Anything set in the request takes precedence over the metadata
+	stream, err := client.JoinStream(ctx, &proto.Hello{Version: "1.1.0"})
+	require.NoError(t, err)
+	header, err := stream.Header()
+	require.NoError(t, err)
+	require.Len(t, header.Get("bot_id"), 1)
+	require.Empty(t, header.Get("session_token"), "JoinStream bots can't resume")
+
+	botInfo := service.BotInfo()
+	require.Len(t, botInfo, 1)
+	require.Equal(t, header.Get("bot_id")[0], botInfo[0].ID.String())
+	require.Equal(t, 5, botInfo[0].MaxChannels)
+	require.Equal(t, "1.1.0", botInfo[0].Version)
+	require.Equal(t, "bot-1", botInfo[0].Hostname)
+	require.Equal(t, "linux/amd64", botInfo[0].Platform)
+	require.Equal(t, protocolJoinStream, botInfo[0].ProtocolVersion)
+	require.Equal(t, map[string]string{"zone": "eu", "account": "modbot"}, botInfo[0].Labels)
+}
+
+func Test_serverJoinStreamRegistration(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata []string
+		code     codes.Code
+	}{
+		{
+			name: "Bots without a protocol version don't have to register",
+			code: codes.OK,
+		},
+		{
+			name:     "Newer protocol version",
+			metadata: []string{"protocol_version", "3"},
+			code:     codes.FailedPrecondition,
+		},
+		{
+			name:     "Registered without a hostname",
+			metadata: []string{"protocol_version", "1", "platform", "linux/amd64"},
+			code:     codes.InvalidArgument,
+		},
+		{
+			name:     "Invalid hostname",
+			metadata: []string{"hostname", "bot 1"},
+			code:     codes.InvalidArgument,
+		},
+		{
+			name:     "Invalid platform",
+			metadata: []string{"platform", "linux"},
+			code:     codes.InvalidArgument,
+		},
+		{
+			name:     "Invalid max_channels",
+			metadata: []string{"max_channels", "lots"},
+			code:     codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := dial(t, bots.New(zap.NewNop()))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			stream, err := client.JoinStream(metadata.AppendToOutgoingContext(ctx, tt.metadata...), &proto.Hello{})
+			require.NoError(t, err)
+			if tt.code == codes.OK {
+				// Joined bots are sent their ID, and the stream stays open until it's cancelled
+				header, err := stream.Header()
+				require.NoError(t, err)
+				require.Len(t, header.Get("bot_id"), 1)
+				return
+			}
+			_, err = stream.Recv()
+			require.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func Test_serverConnectRequiresHello(t *testing.T) {
+	service := bots.New(zap.NewNop())
+	client := dial(t, service)
+	stream, err := client.Connect(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&proto.BotMessage{Payload: &proto.BotMessage_Heartbeat{Heartbeat: &proto.Heartbeat{}}}))
+	_, err = stream.Recv()
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	require.Empty(t, service.BotInfo())
+}
+
+func Test_serverConnectAcks(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithAckTimeout(time.Hour))
+	stream := connect(t, dial(t, service), newHello())
+	require.NoError(t, service.JoinChannel("foo"))
+	require.NoError(t, service.JoinChannel("bar"))
+	commandIDs := make(map[string]string)
+	for i := 0; i < 2; i++ {
+		msg, err := stream.Recv()
+		require.NoError(t, err)
+		join := msg.GetJoin()
+		require.NotNil(t, join)
+		commandIDs[join.Channel] = join.CommandId
+	}
+	joining := func() []string {
+		botInfo := service.BotInfo()
+		require.Len(t, botInfo, 1)
+		return botInfo[0].Joining
+	}
+	require.Equal(t, []string{"bar", "foo"}, joining())
+
+	// Each ack only completes its own command
+	require.NoError(t, stream.Send(&proto.BotMessage{Payload: &proto.BotMessage_Ack{Ack: &proto.Ack{
+		CommandId: commandIDs["foo"],
+		Channel:   "foo",
+	}}}))
+	require.Eventually(t, func() bool {
+		return len(joining()) == 1
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"bar"}, joining())
+
+	// A bot banned from a channel is excluded from it
+	require.NoError(t, stream.Send(&proto.BotMessage{Payload: &proto.BotMessage_Ack{Ack: &proto.Ack{
+		CommandId: commandIDs["bar"],
+		Channel:   "bar",
+		Failure:   proto.Ack_BANNED,
+		Error:     "banned by a moderator",
+	}}}))
+	require.Eventually(t, func() bool {
+		return len(service.Exclusions()) == 1
+	}, time.Second, 10*time.Millisecond)
+	exclusion := service.Exclusions()[0]
+	require.Equal(t, "bar", exclusion.Channel)
+	require.Equal(t, "banned", exclusion.Reason)
+	require.Empty(t, joining())
+}
+
+func Test_serverConnectHeaderAfterJoin(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithAckTimeout(time.Hour))
+	client := dial(t, service)
+	// The queued channel is sent to the bot while it's joining, ahead of its config
+	require.NoError(t, service.JoinChannel("foo"))
+	stream, err := client.Connect(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, stream.Send(&proto.BotMessage{Payload: &proto.BotMessage_Hello{Hello: newHello()}}))
+	header, err := stream.Header()
+	require.NoError(t, err)
+	require.Len(t, header.Get("bot_id"), 1)
+	require.Equal(t, header.Get("bot_id")[0], service.ChannelInfo()["foo"][0].String())
+
+	var join *proto.JoinCommand
+	var config *proto.Config
+	for join == nil || config == nil {
+		msg, err := stream.Recv()
+		require.NoError(t, err)
+		if msg.GetJoin() != nil {
+			join = msg.GetJoin()
+		}
+		if msg.GetConfig() != nil {
+			config = msg.GetConfig()
+		}
+	}
+	require.Equal(t, "foo", join.Channel)
+}
+
+func Test_ackError(t *testing.T) {
+	tests := []struct {
+		name     string
+		ack      *proto.Ack
+		expected error
+		message  string
+	}{
+		{
+			name: "Succeeded",
+			ack:  &proto.Ack{},
+		},
+		{
+			name:    "Untyped failure",
+			ack:     &proto.Ack{Error: "timed out"},
+			message: "timed out",
+		},
+		{
+			name:     "Banned",
+			ack:      &proto.Ack{Failure: proto.Ack_BANNED},
+			expected: bots.ErrBanned,
+			message:  bots.ErrBanned.Error(),
+		},
+		{
+			name:     "Channel not found with a message",
+			ack:      &proto.Ack{Failure: proto.Ack_CHANNEL_NOT_FOUND, Error: "no such channel"},
+			expected: bots.ErrChannelNotFound,
+			message:  "channel not found: no such channel",
+		},
+		{
+			name:     "Channel suspended",
+			ack:      &proto.Ack{Failure: proto.Ack_CHANNEL_SUSPENDED},
+			expected: bots.ErrChannelSuspended,
+			message:  bots.ErrChannelSuspended.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ackError(tt.ack)
+			if tt.message == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.message)
+			for _, typed := range []error{bots.ErrBanned, bots.ErrChannelNotFound, bots.ErrChannelSuspended} {
+				require.Equal(t, typed == tt.expected, errors.Is(err, typed), typed.Error())
+			}
+		})
+	}
+}
+
+func Test_serverJoinStreamAlongsideConnect(t *testing.T) {
+	service := bots.New(zap.NewNop(), bots.WithAckTimeout(time.Hour))
+	client := dial(t, service)
+	hello := newHello()
+	hello.MaxChannels = 1
+	connected := connect(t, client, hello)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	streamed, err := client.JoinStream(metadata.AppendToOutgoingContext(ctx, "max_channels", "1"), &proto.Hello{})
+	require.NoError(t, err)
+	header, err := streamed.Header()
+	require.NoError(t, err)
+	streamedID := uuid.MustParse(header.Get("bot_id")[0])
+	require.Len(t, service.BotInfo(), 2)
+
+	// Each bot has room for one channel, so both are sent a JOIN over their own stream
+	require.NoError(t, service.JoinChannel("foo"))
+	require.NoError(t, service.JoinChannel("bar"))
+	msg, err := connected.Recv()
+	require.NoError(t, err)
+	join := msg.GetJoin()
+	require.NotNil(t, join)
+	payload, err := streamed.Recv()
+	require.NoError(t, err)
+	require.Equal(t, proto.StreamPayload_JOIN, payload.Type)
+	require.ElementsMatch(t, []string{"foo", "bar"}, []string{join.Channel, payload.Channel})
+
+	// The JoinStream bot can't ack, so its JOIN is done once sent while the Connect bot's waits for its ack
+	require.Eventually(t, func() bool {
+		for _, info := range service.BotInfo() {
+			if info.ID == streamedID {
+				return len(info.Joining) == 0
+			}
+		}
+		return false
+	}, time.Second, 10*time.Millisecond)
+	for _, info := range service.BotInfo() {
+		if info.ID == streamedID {
+			require.Equal(t, protocolJoinStream, info.ProtocolVersion)
+		} else {
+			require.Equal(t, protocolConnect, info.ProtocolVersion)
+			require.Equal(t, []string{join.Channel}, info.Joining)
+		}
+	}
+}
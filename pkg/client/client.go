@@ -5,10 +5,36 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/ch629/bot-orchestrator/pkg/proto"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// protocolJoinStream is the version of the orchestrator protocol spoken over JoinStream
+	protocolJoinStream = 1
+	// protocolConnect is the version of the orchestrator protocol spoken over Connect
+	protocolConnect = 2
+)
+
+var (
+	// ErrBanned should be returned by TryJoinChannel when the bot's account is banned from the channel, the
+	// orchestrator tries another bot instead
+	ErrBanned = errors.New("banned from channel")
+	// ErrChannelNotFound should be returned by TryJoinChannel when the channel doesn't exist, the orchestrator stops
+	// trying to join it
+	ErrChannelNotFound = errors.New("channel not found")
+	// ErrChannelSuspended should be returned by TryJoinChannel when the channel has been suspended, the orchestrator
+	// stops trying to join it
+	ErrChannelSuspended = errors.New("channel suspended")
 )
 
 // OrchestratorClient is a client which accepts messages from the orchestrator server
@@ -19,30 +45,188 @@ type OrchestratorClient interface {
 	Close()
 }
 
+// ReportingClient is an OrchestratorClient which reports whether it managed to join or leave a channel, when a client
+// implements it TryJoinChannel and TryLeaveChannel are called instead of JoinChannel and LeaveChannel
+//go:generate mockery --name ReportingClient --disable-version-string
+type ReportingClient interface {
+	OrchestratorClient
+	// TryJoinChannel joins the bot to a channel, the error is reported to the orchestrator so it can retry or use
+	// another bot, wrap ErrBanned, ErrChannelNotFound or ErrChannelSuspended to say why the bot couldn't join
+	TryJoinChannel(channel string) error
+	// TryLeaveChannel leaves a channel, the error is reported to the orchestrator so it can retry
+	TryLeaveChannel(channel string) error
+}
+
+// joinChannel joins the client to a channel, clients which don't report errors always succeed
+func joinChannel(client OrchestratorClient, channel string) error {
+	if reporting, ok := client.(ReportingClient); ok {
+		return reporting.TryJoinChannel(channel)
+	}
+	client.JoinChannel(channel)
+	return nil
+}
+
+// leaveChannel leaves a channel, clients which don't report errors always succeed
+func leaveChannel(client OrchestratorClient, channel string) error {
+	if reporting, ok := client.(ReportingClient); ok {
+		return reporting.TryLeaveChannel(channel)
+	}
+	client.LeaveChannel(channel)
+	return nil
+}
+
+// JoinOption configures what a bot tells the orchestrator about itself when joining
+type JoinOption func(h *proto.Hello)
+
+// WithMaxChannels tells the orchestrator the most channels the bot can be in at once
+func WithMaxChannels(maxChannels int) JoinOption {
+	return func(h *proto.Hello) {
+		h.MaxChannels = int32(maxChannels)
+	}
+}
+
+// WithAccount tells the orchestrator which platform account the bot is using, bots sharing an account share its rate
+// limits
+func WithAccount(account string) JoinOption {
+	return func(h *proto.Hello) {
+		h.Account = account
+	}
+}
+
+// WithLabels tells the orchestrator the labels describing the bot, e.g. region, account, version or platform, which
+// channels can use to choose which bots run them
+func WithLabels(labels map[string]string) JoinOption {
+	return func(h *proto.Hello) {
+		if h.Labels == nil {
+			h.Labels = make(map[string]string, len(labels))
+		}
+		for k, v := range labels {
+			h.Labels[k] = v
+		}
+	}
+}
+
+// WithZone tells the orchestrator which topology domain the bot runs in, a channel's replicas are spread across
+// different zones where possible
+func WithZone(zone string) JoinOption {
+	return WithLabels(map[string]string{"zone": zone})
+}
+
+// WithVersion tells the orchestrator which build of the bot is running, so new versions can be rolled out to a subset
+// of channels first
+func WithVersion(version string) JoinOption {
+	return func(h *proto.Hello) {
+		h.Version = version
+	}
+}
+
+// WithSession resumes a previous session after reconnecting, so the bot keeps its ID and the channels it was running
+// If the session can't be resumed any more the bot joins as a new bot, only bots using Connect can resume
+func WithSession(id uuid.UUID, token string) JoinOption {
+	return func(h *proto.Hello) {
+		h.BotId = id.String()
+		h.SessionToken = token
+	}
+}
+
+// WithHostname overrides the hostname the bot registers with, which defaults to the host's name
+func WithHostname(hostname string) JoinOption {
+	return func(h *proto.Hello) {
+		h.Hostname = hostname
+	}
+}
+
+// WithPlatform overrides the os/arch the bot registers with, which defaults to the platform it was built for
+func WithPlatform(platform string) JoinOption {
+	return func(h *proto.Hello) {
+		h.Platform = platform
+	}
+}
+
+// newHello builds the Hello describing a bot speaking a protocol version from its options
+func newHello(protocolVersion int32, opts []JoinOption) *proto.Hello {
+	hostname, _ := os.Hostname()
+	hello := &proto.Hello{
+		Hostname:        hostname,
+		Platform:        runtime.GOOS + "/" + runtime.GOARCH,
+		ProtocolVersion: protocolVersion,
+	}
+	for _, opt := range opts {
+		opt(hello)
+	}
+	return hello
+}
+
+// helloMetadata converts a Hello into the metadata JoinStream expects
+func helloMetadata(hello *proto.Hello) metadata.MD {
+	md := metadata.MD{}
+	if hello.MaxChannels != 0 {
+		md.Set("max_channels", strconv.Itoa(int(hello.MaxChannels)))
+	}
+	if hello.Account != "" {
+		md.Set("account", hello.Account)
+	}
+	if hello.Version != "" {
+		md.Set("version", hello.Version)
+	}
+	if hello.Hostname != "" {
+		md.Set("hostname", hello.Hostname)
+	}
+	if hello.Platform != "" {
+		md.Set("platform", hello.Platform)
+	}
+	if hello.ProtocolVersion != 0 {
+		md.Set("protocol_version", strconv.Itoa(int(hello.ProtocolVersion)))
+	}
+	if hello.BotId != "" {
+		md.Set("bot_id", hello.BotId)
+	}
+	if hello.SessionToken != "" {
+		md.Set("session_token", hello.SessionToken)
+	}
+	for k, v := range hello.Labels {
+		md.Append("labels", k+"="+v)
+	}
+	return md
+}
+
+// headerSession reads the ID and session token the orchestrator gave the bot from the stream's header
+func headerSession(stream grpc.ClientStream) (uuid.UUID, string, error) {
+	md, err := stream.Header()
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("Header: %w", err)
+	}
+	id, ok := md["bot_id"]
+	if !ok || len(id) == 0 {
+		return uuid.Nil, "", errors.New("no ID provided")
+	}
+	botID, err := uuid.Parse(id[0])
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("parse bot_id as UUID: %w", err)
+	}
+	var token string
+	if values := md["session_token"]; len(values) > 0 {
+		token = values[0]
+	}
+	return botID, token, nil
+}
+
 // Join joins a bot to the orchestrator
-// TODO: Call opts?
 // TODO: Check that cancelling the ctx closes the bot connection properly
-func Join(ctx context.Context, conn *grpc.ClientConn, client OrchestratorClient) (*uuid.UUID, error) {
+//
+// Deprecated: Join can't send anything back to the orchestrator over its stream, use Connect instead
+func Join(ctx context.Context, conn *grpc.ClientConn, client OrchestratorClient, opts ...JoinOption) (*uuid.UUID, error) {
+	hello := newHello(protocolJoinStream, opts)
 	grpcClient := proto.NewOrchestratorClient(conn)
-	stream, err := grpcClient.JoinStream(ctx, &proto.EmptyMessage{})
+	// The metadata describes the bot to orchestrators which don't read the request
+	stream, err := grpcClient.JoinStream(metadata.NewOutgoingContext(ctx, helloMetadata(hello)), hello)
 	if err != nil {
 		return nil, fmt.Errorf("JoinStream: %w", err)
 	}
 
-	// Get ID from header
-	var botID uuid.UUID
-	{
-		md, err := stream.Header()
-		if err != nil {
-			return nil, fmt.Errorf("Header: %w", err)
-		}
-		id, ok := md["bot_id"]
-		if !ok || len(id) == 0 {
-			return nil, errors.New("no ID provided")
-		}
-		if botID, err = uuid.Parse(id[0]); err != nil {
-			return nil, fmt.Errorf("parse bot_id as UUID: %w", err)
-		}
+	botID, _, err := headerSession(stream)
+	if err != nil {
+		return nil, err
 	}
 
 	go func() {
@@ -55,12 +239,236 @@ func Join(ctx context.Context, conn *grpc.ClientConn, client OrchestratorClient)
 
 			switch resp.Type {
 			case proto.StreamPayload_JOIN:
-				client.JoinChannel(resp.Channel)
+				if err := joinChannel(client, resp.Channel); err != nil {
+					// JoinStream can't tell the orchestrator the JOIN failed
+					zap.L().Warn("failed to join channel", zap.String("channel", resp.Channel), zap.Error(err))
+					continue
+				}
+				// Let the orchestrator know we're in the channel, so any bot we're replacing can leave
+				if _, err := grpcClient.ConfirmJoin(ctx, &proto.JoinConfirmation{
+					BotId:   botID.String(),
+					Channel: resp.Channel,
+				}); err != nil {
+					zap.L().Warn("failed to confirm join", zap.String("channel", resp.Channel), zap.Error(err))
+				}
 			case proto.StreamPayload_LEAVE:
-				client.LeaveChannel(resp.Channel)
+				if err := leaveChannel(client, resp.Channel); err != nil {
+					zap.L().Warn("failed to leave channel", zap.String("channel", resp.Channel), zap.Error(err))
+				}
 			}
 		}
 	}()
 
 	return &botID, nil
 }
+
+// Session is a bot's connection to the orchestrator over a bidirectional stream
+type Session struct {
+	id     uuid.UUID
+	token  string
+	stream proto.Orchestrator_ConnectClient
+	// mux stops messages being sent on the stream concurrently
+	mux             sync.Mutex
+	metricsInterval time.Duration
+	// heartbeatInterval is how often heartbeats are sent, stopHeartbeats stops sending them
+	heartbeatInterval time.Duration
+	stopHeartbeats    context.CancelFunc
+}
+
+// Connect connects a bot to the orchestrator, commands are passed to client until the orchestrator shuts the bot down
+// or ctx is done, then client is closed
+// Commands are acknowledged once client has carried them out, with the error if it couldn't
+func Connect(ctx context.Context, conn *grpc.ClientConn, client OrchestratorClient, opts ...JoinOption) (*Session, error) {
+	stream, err := proto.NewOrchestratorClient(conn).Connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Connect: %w", err)
+	}
+	if err := stream.Send(&proto.BotMessage{
+		Payload: &proto.BotMessage_Hello{Hello: newHello(protocolConnect, opts)},
+	}); err != nil {
+		return nil, fmt.Errorf("send Hello: %w", err)
+	}
+	botID, token, err := headerSession(stream)
+	if err != nil {
+		return nil, err
+	}
+	session := &Session{
+		id:     botID,
+		token:  token,
+		stream: stream,
+	}
+	go session.run(client)
+	return session, nil
+}
+
+// ID returns the ID the orchestrator gave the bot
+func (s *Session) ID() uuid.UUID {
+	return s.id
+}
+
+// Token returns the session token the bot can resume its session with after reconnecting, see WithSession
+func (s *Session) Token() string {
+	return s.token
+}
+
+// MetricsInterval returns how often the orchestrator wants the bot to report metrics, 0 until it has said
+func (s *Session) MetricsInterval() time.Duration {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.metricsInterval
+}
+
+// ReportMetrics sends the messages per second the bot is receiving on each of its channels to the orchestrator
+func (s *Session) ReportMetrics(rates map[string]float64) error {
+	return s.send(&proto.BotMessage{
+		Payload: &proto.BotMessage_Metrics{Metrics: &proto.Metrics{ChannelRates: rates}},
+	})
+}
+
+// ReportError tells the orchestrator something has gone wrong, channel can be empty if it isn't about a channel
+func (s *Session) ReportError(channel string, err error) error {
+	return s.send(&proto.BotMessage{
+		Payload: &proto.BotMessage_Error{Error: &proto.Error{
+			Channel: channel,
+			Message: err.Error(),
+		}},
+	})
+}
+
+// Heartbeat tells the orchestrator the bot is still alive, heartbeats are sent automatically as often as the
+// orchestrator asks for them
+func (s *Session) Heartbeat() error {
+	return s.send(&proto.BotMessage{
+		Payload: &proto.BotMessage_Heartbeat{Heartbeat: &proto.Heartbeat{}},
+	})
+}
+
+// Close disconnects the bot from the orchestrator
+func (s *Session) Close() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.stream.CloseSend()
+}
+
+func (s *Session) send(msg *proto.BotMessage) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.stream.Send(msg)
+}
+
+// ack tells the orchestrator whether a command succeeded, the command is sent again if it failed
+func (s *Session) ack(commandID, channel string, err error) {
+	ack := &proto.Ack{
+		Channel:   channel,
+		CommandId: commandID,
+	}
+	if err != nil {
+		ack.Error = err.Error()
+		ack.Failure = ackFailure(err)
+	}
+	if err := s.send(&proto.BotMessage{
+		Payload: &proto.BotMessage_Ack{Ack: ack},
+	}); err != nil {
+		zap.L().Warn("failed to acknowledge command", zap.String("channel", channel), zap.String("command_id", commandID), zap.Error(err))
+	}
+}
+
+// ackFailure classifies why a command failed so the orchestrator knows whether to retry it
+func ackFailure(err error) proto.Ack_Failure {
+	switch {
+	case errors.Is(err, ErrBanned):
+		return proto.Ack_BANNED
+	case errors.Is(err, ErrChannelNotFound):
+		return proto.Ack_CHANNEL_NOT_FOUND
+	case errors.Is(err, ErrChannelSuspended):
+		return proto.Ack_CHANNEL_SUSPENDED
+	default:
+		return proto.Ack_UNKNOWN
+	}
+}
+
+// configure applies the configuration the orchestrator sent, restarting heartbeats if their interval changed
+func (s *Session) configure(config *proto.Config) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.metricsInterval = time.Duration(config.MetricsIntervalMs) * time.Millisecond
+	interval := time.Duration(config.HeartbeatIntervalMs) * time.Millisecond
+	if interval == s.heartbeatInterval {
+		return
+	}
+	s.heartbeatInterval = interval
+	if s.stopHeartbeats != nil {
+		s.stopHeartbeats()
+		s.stopHeartbeats = nil
+	}
+	if interval <= 0 {
+		return
+	}
+	ctx, cancel := context.WithCancel(s.stream.Context())
+	s.stopHeartbeats = cancel
+	go s.sendHeartbeats(ctx, interval)
+}
+
+// sendHeartbeats sends a heartbeat every interval until ctx is done
+func (s *Session) sendHeartbeats(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Heartbeat(); err != nil {
+				zap.L().Warn("failed to send heartbeat", zap.Error(err))
+			}
+		}
+	}
+}
+
+// run passes the orchestrator's commands to client until the stream ends
+func (s *Session) run(client OrchestratorClient) {
+	defer client.Close()
+	defer func() {
+		s.mux.Lock()
+		defer s.mux.Unlock()
+		if s.stopHeartbeats != nil {
+			s.stopHeartbeats()
+		}
+	}()
+	for {
+		msg, err := s.stream.Recv()
+		if err != nil {
+			return
+		}
+		switch payload := msg.Payload.(type) {
+		case *proto.OrchestratorMessage_Join:
+			err := joinChannel(client, payload.Join.Channel)
+			// Let the orchestrator know whether we're in the channel, so any bot we're replacing can leave
+			s.ack(payload.Join.CommandId, payload.Join.Channel, err)
+		case *proto.OrchestratorMessage_Leave:
+			err := leaveChannel(client, payload.Leave.Channel)
+			s.ack(payload.Leave.CommandId, payload.Leave.Channel, err)
+		case *proto.OrchestratorMessage_Config:
+			s.configure(payload.Config)
+		case *proto.OrchestratorMessage_Shutdown:
+			zap.L().Info("shut down by orchestrator", zap.String("reason", payload.Shutdown.Reason))
+			if err := s.Close(); err != nil {
+				zap.L().Warn("failed to close stream", zap.Error(err))
+			}
+			return
+		}
+	}
+}
+
+// ReportMetrics sends the messages per second the bot is receiving on each of its channels to the orchestrator
+// Bots using Connect should use Session.ReportMetrics instead
+func ReportMetrics(ctx context.Context, conn *grpc.ClientConn, botID uuid.UUID, rates map[string]float64) error {
+	grpcClient := proto.NewOrchestratorClient(conn)
+	if _, err := grpcClient.ReportMetrics(ctx, &proto.MetricsReport{
+		BotId:        botID.String(),
+		ChannelRates: rates,
+	}); err != nil {
+		return fmt.Errorf("ReportMetrics: %w", err)
+	}
+	return nil
+}
@@ -2,7 +2,10 @@ package client_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net"
+	"runtime"
 	"testing"
 	"time"
 
@@ -10,6 +13,7 @@ import (
 	"github.com/ch629/bot-orchestrator/pkg/client/mocks"
 	"github.com/ch629/bot-orchestrator/pkg/proto"
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
@@ -18,13 +22,73 @@ import (
 
 type server struct {
 	proto.UnimplementedOrchestratorServer
+	reports []*proto.MetricsReport
+	// joins are sent to each bot that joins
+	joins    []string
+	confirms chan *proto.JoinConfirmation
+	// hellos and received are the messages bots send over Connect
+	hellos   chan *proto.Hello
+	received chan *proto.BotMessage
+	// heartbeatInterval is the heartbeat interval sent to bots in their config
+	heartbeatInterval time.Duration
 }
 
-func (s *server) JoinStream(_ *proto.EmptyMessage, resp proto.Orchestrator_JoinStreamServer) error {
+func (s *server) Connect(stream proto.Orchestrator_ConnectServer) error {
+	msg, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	s.hellos <- msg.GetHello()
+	stream.SendHeader(metadata.Pairs("bot_id", uuid.NewString(), "session_token", "token"))
+	if err := stream.Send(&proto.OrchestratorMessage{
+		Payload: &proto.OrchestratorMessage_Config{Config: &proto.Config{
+			MetricsIntervalMs:   5000,
+			HeartbeatIntervalMs: s.heartbeatInterval.Milliseconds(),
+		}},
+	}); err != nil {
+		return err
+	}
+	for _, channel := range s.joins {
+		if err := stream.Send(&proto.OrchestratorMessage{
+			Payload: &proto.OrchestratorMessage_Join{Join: &proto.JoinCommand{Channel: channel, CommandId: channel + "-join"}},
+		}); err != nil {
+			return err
+		}
+	}
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		s.received <- msg
+		if msg.GetError() != nil {
+			return stream.Send(&proto.OrchestratorMessage{
+				Payload: &proto.OrchestratorMessage_Shutdown{Shutdown: &proto.Shutdown{Reason: "error"}},
+			})
+		}
+	}
+}
+
+func (s *server) JoinStream(_ *proto.Hello, resp proto.Orchestrator_JoinStreamServer) error {
 	resp.SendHeader(metadata.Pairs("bot_id", uuid.NewString()))
+	for _, channel := range s.joins {
+		if err := resp.Send(&proto.StreamPayload{Type: proto.StreamPayload_JOIN, Channel: channel}); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func (s *server) ConfirmJoin(_ context.Context, req *proto.JoinConfirmation) (*proto.EmptyMessage, error) {
+	s.confirms <- req
+	return &proto.EmptyMessage{}, nil
+}
+
+func (s *server) ReportMetrics(_ context.Context, req *proto.MetricsReport) (*proto.EmptyMessage, error) {
+	s.reports = append(s.reports, req)
+	return &proto.EmptyMessage{}, nil
+}
+
 func bufDialer(lis *bufconn.Listener) func(ctx context.Context, addr string) (net.Conn, error) {
 	return func(ctx context.Context, addr string) (net.Conn, error) {
 		return lis.Dial()
@@ -53,3 +117,185 @@ func TestJoin(t *testing.T) {
 	time.Sleep(10 * time.Millisecond)
 	mockOrchestratorClient.AssertExpectations(t)
 }
+
+func TestReportMetrics(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	srv := &server{}
+	proto.RegisterOrchestratorServer(s, srv)
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(bufDialer(lis)), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	id := uuid.New()
+	require.NoError(t, client.ReportMetrics(context.Background(), conn, id, map[string]float64{"foo": 1.5}))
+	require.Len(t, srv.reports, 1)
+	require.Equal(t, id.String(), srv.reports[0].BotId)
+	require.Equal(t, map[string]float64{"foo": 1.5}, srv.reports[0].ChannelRates)
+}
+
+func TestJoinConfirmsChannels(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	srv := &server{
+		joins:    []string{"foo"},
+		confirms: make(chan *proto.JoinConfirmation, 1),
+	}
+	proto.RegisterOrchestratorServer(s, srv)
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(bufDialer(lis)), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	mockOrchestratorClient := &mocks.OrchestratorClient{}
+	mockOrchestratorClient.On("JoinChannel", "foo")
+	mockOrchestratorClient.On("Close")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	id, err := client.Join(ctx, conn, mockOrchestratorClient)
+	require.NoError(t, err)
+	select {
+	case confirm := <-srv.confirms:
+		require.Equal(t, id.String(), confirm.BotId)
+		require.Equal(t, "foo", confirm.Channel)
+	case <-time.After(time.Second):
+		require.Fail(t, "join was never confirmed")
+	}
+	mockOrchestratorClient.AssertCalled(t, "JoinChannel", "foo")
+}
+
+func TestConnect(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	srv := &server{
+		joins:    []string{"foo", "bar"},
+		hellos:   make(chan *proto.Hello, 1),
+		received: make(chan *proto.BotMessage, 4),
+	}
+	proto.RegisterOrchestratorServer(s, srv)
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(bufDialer(lis)), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	closed := make(chan struct{})
+	// Reporting clients tell the orchestrator why they couldn't join
+	mockOrchestratorClient := &mocks.ReportingClient{}
+	mockOrchestratorClient.On("TryJoinChannel", "foo").Return(nil)
+	mockOrchestratorClient.On("TryJoinChannel", "bar").Return(fmt.Errorf("modbot: %w", client.ErrBanned))
+	mockOrchestratorClient.On("Close").Run(func(mock.Arguments) {
+		close(closed)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session, err := client.Connect(ctx, conn, mockOrchestratorClient, client.WithMaxChannels(2), client.WithZone("eu-west-1a"), client.WithHostname("bot-1"))
+	require.NoError(t, err)
+	require.NotEqual(t, uuid.Nil, session.ID())
+	require.Equal(t, "token", session.Token())
+	hello := <-srv.hellos
+	require.Empty(t, hello.BotId)
+	require.Equal(t, int32(2), hello.MaxChannels)
+	require.Equal(t, map[string]string{"zone": "eu-west-1a"}, hello.Labels)
+	require.Equal(t, "bot-1", hello.Hostname)
+	require.Equal(t, runtime.GOOS+"/"+runtime.GOARCH, hello.Platform)
+	require.Equal(t, int32(2), hello.ProtocolVersion)
+
+	receive := func() *proto.BotMessage {
+		select {
+		case msg := <-srv.received:
+			return msg
+		case <-time.After(time.Second):
+			require.Fail(t, "timed out waiting for message")
+			return nil
+		}
+	}
+	ack := receive().GetAck()
+	require.Equal(t, "foo", ack.GetChannel())
+	require.Equal(t, "foo-join", ack.GetCommandId())
+	require.Empty(t, ack.GetError())
+	// Failures are acknowledged too, with why the bot couldn't join so the orchestrator knows whether to retry
+	ack = receive().GetAck()
+	require.Equal(t, "bar-join", ack.GetCommandId())
+	require.Equal(t, "modbot: banned from channel", ack.GetError())
+	require.Equal(t, proto.Ack_BANNED, ack.GetFailure())
+	require.Equal(t, 5*time.Second, session.MetricsInterval())
+
+	require.NoError(t, session.ReportMetrics(map[string]float64{"foo": 1.5}))
+	require.Equal(t, map[string]float64{"foo": 1.5}, receive().GetMetrics().GetChannelRates())
+
+	// The server shuts the bot down after it reports an error
+	require.NoError(t, session.ReportError("foo", errors.New("banned")))
+	require.Equal(t, "banned", receive().GetError().GetMessage())
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		require.Fail(t, "client was never closed")
+	}
+	mockOrchestratorClient.AssertCalled(t, "TryJoinChannel", "foo")
+	mockOrchestratorClient.AssertNotCalled(t, "JoinChannel", "foo")
+}
+
+func TestConnectResumesSession(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	srv := &server{
+		hellos:   make(chan *proto.Hello, 1),
+		received: make(chan *proto.BotMessage, 1),
+	}
+	proto.RegisterOrchestratorServer(s, srv)
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(bufDialer(lis)), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	mockOrchestratorClient := &mocks.OrchestratorClient{}
+	mockOrchestratorClient.On("Close").Maybe()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	id := uuid.New()
+	_, err = client.Connect(ctx, conn, mockOrchestratorClient, client.WithSession(id, "previous"))
+	require.NoError(t, err)
+	hello := <-srv.hellos
+	require.Equal(t, id.String(), hello.BotId)
+	require.Equal(t, "previous", hello.SessionToken)
+}
+
+func TestConnectHeartbeats(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	srv := &server{
+		hellos:            make(chan *proto.Hello, 1),
+		received:          make(chan *proto.BotMessage, 10),
+		heartbeatInterval: 10 * time.Millisecond,
+	}
+	proto.RegisterOrchestratorServer(s, srv)
+	go s.Serve(lis)
+	defer s.Stop()
+	conn, err := grpc.DialContext(context.Background(), "bufnet", grpc.WithContextDialer(bufDialer(lis)), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	mockOrchestratorClient := &mocks.OrchestratorClient{}
+	mockOrchestratorClient.On("Close")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err = client.Connect(ctx, conn, mockOrchestratorClient)
+	require.NoError(t, err)
+
+	// Heartbeats are sent without being asked for each one
+	for i := 0; i < 2; i++ {
+		select {
+		case msg := <-srv.received:
+			require.NotNil(t, msg.GetHeartbeat())
+		case <-time.After(time.Second):
+			require.Fail(t, "timed out waiting for heartbeat")
+		}
+	}
+}
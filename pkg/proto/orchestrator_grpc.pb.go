@@ -18,7 +18,15 @@ const _ = grpc.SupportPackageIsVersion7
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type OrchestratorClient interface {
-	JoinStream(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (Orchestrator_JoinStreamClient, error)
+	// JoinStream only sends commands to the bot, Connect should be used instead so the bot can talk back
+	// The bot describes itself in the request, older bots which send an EmptyMessage can describe themselves in the
+	// request metadata instead
+	JoinStream(ctx context.Context, in *Hello, opts ...grpc.CallOption) (Orchestrator_JoinStreamClient, error)
+	// Connect is a bidirectional stream between a bot and the orchestrator, the bot must send a Hello first
+	// The bot_id and session_token headers are sent back, the session token lets the bot resume after reconnecting
+	Connect(ctx context.Context, opts ...grpc.CallOption) (Orchestrator_ConnectClient, error)
+	ReportMetrics(ctx context.Context, in *MetricsReport, opts ...grpc.CallOption) (*EmptyMessage, error)
+	ConfirmJoin(ctx context.Context, in *JoinConfirmation, opts ...grpc.CallOption) (*EmptyMessage, error)
 }
 
 type orchestratorClient struct {
@@ -29,7 +37,7 @@ func NewOrchestratorClient(cc grpc.ClientConnInterface) OrchestratorClient {
 	return &orchestratorClient{cc}
 }
 
-func (c *orchestratorClient) JoinStream(ctx context.Context, in *EmptyMessage, opts ...grpc.CallOption) (Orchestrator_JoinStreamClient, error) {
+func (c *orchestratorClient) JoinStream(ctx context.Context, in *Hello, opts ...grpc.CallOption) (Orchestrator_JoinStreamClient, error) {
 	stream, err := c.cc.NewStream(ctx, &Orchestrator_ServiceDesc.Streams[0], "/Orchestrator/JoinStream", opts...)
 	if err != nil {
 		return nil, err
@@ -61,11 +69,68 @@ func (x *orchestratorJoinStreamClient) Recv() (*StreamPayload, error) {
 	return m, nil
 }
 
+func (c *orchestratorClient) Connect(ctx context.Context, opts ...grpc.CallOption) (Orchestrator_ConnectClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Orchestrator_ServiceDesc.Streams[1], "/Orchestrator/Connect", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &orchestratorConnectClient{stream}
+	return x, nil
+}
+
+type Orchestrator_ConnectClient interface {
+	Send(*BotMessage) error
+	Recv() (*OrchestratorMessage, error)
+	grpc.ClientStream
+}
+
+type orchestratorConnectClient struct {
+	grpc.ClientStream
+}
+
+func (x *orchestratorConnectClient) Send(m *BotMessage) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *orchestratorConnectClient) Recv() (*OrchestratorMessage, error) {
+	m := new(OrchestratorMessage)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *orchestratorClient) ReportMetrics(ctx context.Context, in *MetricsReport, opts ...grpc.CallOption) (*EmptyMessage, error) {
+	out := new(EmptyMessage)
+	err := c.cc.Invoke(ctx, "/Orchestrator/ReportMetrics", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *orchestratorClient) ConfirmJoin(ctx context.Context, in *JoinConfirmation, opts ...grpc.CallOption) (*EmptyMessage, error) {
+	out := new(EmptyMessage)
+	err := c.cc.Invoke(ctx, "/Orchestrator/ConfirmJoin", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // OrchestratorServer is the server API for Orchestrator service.
 // All implementations must embed UnimplementedOrchestratorServer
 // for forward compatibility
 type OrchestratorServer interface {
-	JoinStream(*EmptyMessage, Orchestrator_JoinStreamServer) error
+	// JoinStream only sends commands to the bot, Connect should be used instead so the bot can talk back
+	// The bot describes itself in the request, older bots which send an EmptyMessage can describe themselves in the
+	// request metadata instead
+	JoinStream(*Hello, Orchestrator_JoinStreamServer) error
+	// Connect is a bidirectional stream between a bot and the orchestrator, the bot must send a Hello first
+	// The bot_id and session_token headers are sent back, the session token lets the bot resume after reconnecting
+	Connect(Orchestrator_ConnectServer) error
+	ReportMetrics(context.Context, *MetricsReport) (*EmptyMessage, error)
+	ConfirmJoin(context.Context, *JoinConfirmation) (*EmptyMessage, error)
 	mustEmbedUnimplementedOrchestratorServer()
 }
 
@@ -73,9 +138,18 @@ type OrchestratorServer interface {
 type UnimplementedOrchestratorServer struct {
 }
 
-func (UnimplementedOrchestratorServer) JoinStream(*EmptyMessage, Orchestrator_JoinStreamServer) error {
+func (UnimplementedOrchestratorServer) JoinStream(*Hello, Orchestrator_JoinStreamServer) error {
 	return status.Errorf(codes.Unimplemented, "method JoinStream not implemented")
 }
+func (UnimplementedOrchestratorServer) Connect(Orchestrator_ConnectServer) error {
+	return status.Errorf(codes.Unimplemented, "method Connect not implemented")
+}
+func (UnimplementedOrchestratorServer) ReportMetrics(context.Context, *MetricsReport) (*EmptyMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportMetrics not implemented")
+}
+func (UnimplementedOrchestratorServer) ConfirmJoin(context.Context, *JoinConfirmation) (*EmptyMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmJoin not implemented")
+}
 func (UnimplementedOrchestratorServer) mustEmbedUnimplementedOrchestratorServer() {}
 
 // UnsafeOrchestratorServer may be embedded to opt out of forward compatibility for this service.
@@ -90,7 +164,7 @@ func RegisterOrchestratorServer(s grpc.ServiceRegistrar, srv OrchestratorServer)
 }
 
 func _Orchestrator_JoinStream_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(EmptyMessage)
+	m := new(Hello)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
@@ -110,19 +184,96 @@ func (x *orchestratorJoinStreamServer) Send(m *StreamPayload) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Orchestrator_Connect_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(OrchestratorServer).Connect(&orchestratorConnectServer{stream})
+}
+
+type Orchestrator_ConnectServer interface {
+	Send(*OrchestratorMessage) error
+	Recv() (*BotMessage, error)
+	grpc.ServerStream
+}
+
+type orchestratorConnectServer struct {
+	grpc.ServerStream
+}
+
+func (x *orchestratorConnectServer) Send(m *OrchestratorMessage) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *orchestratorConnectServer) Recv() (*BotMessage, error) {
+	m := new(BotMessage)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _Orchestrator_ReportMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetricsReport)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServer).ReportMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Orchestrator/ReportMetrics",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServer).ReportMetrics(ctx, req.(*MetricsReport))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Orchestrator_ConfirmJoin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JoinConfirmation)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrchestratorServer).ConfirmJoin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Orchestrator/ConfirmJoin",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrchestratorServer).ConfirmJoin(ctx, req.(*JoinConfirmation))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Orchestrator_ServiceDesc is the grpc.ServiceDesc for Orchestrator service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var Orchestrator_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "Orchestrator",
 	HandlerType: (*OrchestratorServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ReportMetrics",
+			Handler:    _Orchestrator_ReportMetrics_Handler,
+		},
+		{
+			MethodName: "ConfirmJoin",
+			Handler:    _Orchestrator_ConfirmJoin_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "JoinStream",
 			Handler:       _Orchestrator_JoinStream_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "Connect",
+			Handler:       _Orchestrator_Connect_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
 	},
 	Metadata: "pkg/proto/orchestrator.proto",
 }